@@ -0,0 +1,197 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// calendarMacro expands a builtin calendar macro relative to now, which is the
+// current time in the time zone of the caller.
+type calendarMacro func(m *Macro, now time.Time) (*Term, termType, error)
+
+var (
+	// calendarMacros maps the names of the builtin calendar macros to the
+	// functions that expand them. #now is only expanded client-side when a
+	// clock is provided.
+	calendarMacros = map[string]calendarMacro{
+		"#now":     expandNow,
+		"#today":   expandToday,
+		"#startof": expandStartOf,
+		"#endof":   expandEndOf,
+		"#ago":     expandAgo,
+	}
+
+	// calendarUnits contains the units accepted by #startof and #endof.
+	calendarUnits = []string{"day", "week", "month", "quarter", "year"}
+)
+
+// expandedClientSide returns a Boolean value indicating whether or not the macro
+// with the specified name is expanded client-side when rendering with ro, i.e.
+// whether it is a calendar macro other than #now, or #now and ro has a clock.
+func expandedClientSide(name string, ro *RenderingOptions) bool {
+	if name == "#now" {
+		return ro != nil && ro.GetClock() != nil
+	}
+
+	return calendarMacros[name] != nil
+}
+
+// expandCalendarMacro expands the calendar macro m relative to now, and returns
+// the literal term it expands to along with its type. Both dates and datetimes
+// are expressed as wall clock times in the time zone of now.
+func expandCalendarMacro(m *Macro, now time.Time) (*Term, termType, error) {
+	cm, ok := calendarMacros[m.Name]
+	if !ok {
+		return nil, undefType, errors.Errorf("macro %s is not a calendar macro", m.Name)
+	}
+
+	return cm(m, now)
+}
+
+// expandNow expands #now to the current time.
+func expandNow(m *Macro, now time.Time) (*Term, termType, error) {
+	if err := expectNoArgs(m); err != nil {
+		return nil, undefType, err
+	}
+
+	return wallClockTerm(now), dateTimeType, nil
+}
+
+// expandToday expands #today to the current date.
+func expandToday(m *Macro, now time.Time) (*Term, termType, error) {
+	if err := expectNoArgs(m); err != nil {
+		return nil, undefType, err
+	}
+
+	t, err := literalTerm(now, dateType)
+	return t, dateType, err
+}
+
+// expandStartOf expands #startof to the first instant of the current day,
+// week, month, quarter, or year.
+func expandStartOf(m *Macro, now time.Time) (*Term, termType, error) {
+	u, err := calendarUnitOf(m)
+	if err != nil {
+		return nil, undefType, err
+	}
+
+	return wallClockTerm(startOf(now, u)), dateTimeType, nil
+}
+
+// expandEndOf expands #endof to the last instant of the current day, week,
+// month, quarter, or year, with microsecond precision.
+func expandEndOf(m *Macro, now time.Time) (*Term, termType, error) {
+	u, err := calendarUnitOf(m)
+	if err != nil {
+		return nil, undefType, err
+	}
+
+	s := startOf(now, u)
+
+	switch u {
+	case "day":
+		s = s.AddDate(0, 0, 1)
+	case "week":
+		s = s.AddDate(0, 0, 7)
+	case "month":
+		s = s.AddDate(0, 1, 0)
+	case "quarter":
+		s = s.AddDate(0, 3, 0)
+	case "year":
+		s = s.AddDate(1, 0, 0)
+	}
+
+	return wallClockTerm(s.Add(-time.Microsecond)), dateTimeType, nil
+}
+
+// expandAgo expands #ago to the current time minus the sum of the ISO-8601
+// intervals passed as arguments. Like in SQL, years and months are subtracted
+// first, then weeks and days, and finally hours, minutes, and seconds.
+func expandAgo(m *Macro, now time.Time) (*Term, termType, error) {
+	d, err := toDuration(m)
+	if err != nil {
+		return nil, undefType, err
+	}
+
+	ago := addMonths(now, -(d.Years*12+d.Months)).AddDate(0, 0, -(d.Weeks*7 + d.Days))
+	ago = ago.Add(-(time.Duration(d.Hours)*time.Hour + time.Duration(d.Minutes)*time.Minute +
+		time.Duration(d.Seconds)*time.Second + time.Duration(d.Microseconds)*time.Microsecond))
+
+	return wallClockTerm(ago), dateTimeType, nil
+}
+
+// expectNoArgs returns an error if m is called with arguments, e.g. #now(1),
+// regardless of whether it is expanded client-side or by the target engine.
+func expectNoArgs(m *Macro) error {
+	if len(m.Args) > 0 {
+		return errors.Errorf("macro %s expects 0 arguments, got %d", m.Name, len(m.Args))
+	}
+
+	return nil
+}
+
+// calendarUnitOf returns the calendar unit passed as argument to m.
+func calendarUnitOf(m *Macro) (string, error) {
+	if len(m.Args) != 1 {
+		return "", errors.Errorf("macro %s expects 1 argument, got %d", m.Name, len(m.Args))
+	} else if m.Args[0].String == nil {
+		return "", errors.Errorf("calendar unit cannot be of type %s", toTypeName(termTypeOf(m.Args[0])))
+	}
+
+	u := *m.Args[0].String
+	for _, cu := range calendarUnits {
+		if u == cu {
+			return u, nil
+		}
+	}
+
+	return "", errors.Errorf("invalid calendar unit %s, expected one of %s", u, strings.Join(calendarUnits, ", "))
+}
+
+// addMonths returns t plus the specified number of months, where the day of
+// the month is clamped to the last day of the resulting month, e.g. March 31
+// minus one month is February 29 in leap years.
+func addMonths(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+
+	first := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+
+	return first.AddDate(0, 0, d-1)
+}
+
+// startOf returns the first instant of the calendar unit u that contains t,
+// where weeks start on Monday.
+func startOf(t time.Time, u string) time.Time {
+	y, m, d := t.Date()
+
+	switch u {
+	case "week":
+		d -= (int(t.Weekday()) + 6) % 7
+	case "month":
+		d = 1
+	case "quarter":
+		m, d = m-(m-1)%3, 1
+	case "year":
+		m, d = time.January, 1
+	}
+
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// wallClockTerm returns the datetime literal that denotes the wall clock time
+// of t, i.e. without time zone offset.
+func wallClockTerm(t time.Time) *Term {
+	s := t.Format("2006-01-02T15:04:05.999999999")
+	return &Term{DateTime: &s}
+}
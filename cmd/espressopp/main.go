@@ -21,20 +21,35 @@ var cli struct {
 		Expression        string            `arg name:"expression" help:"Source expression." name:"expression"`
 		FieldMap          map[string]string `arg optional name:"fieldmap" help:"Mapping to native column names." type:"string:string"`
 		EnableNamedParams bool              `help:"Enable named parameters." short:"e"`
+		Dialect           string            `help:"SQL dialect (generic, postgres, mysql, sqlite, sqlserver, oracle)." short:"d" default:"generic"`
+		Placeholders      string            `help:"Placeholder style (dialect, colon, dollar, question, at)." short:"p" default:"dialect"`
 	} `cmd help:"Generate target native query."`
 }
 
-// emitSql renders SQL from e applying m.
-func emitSql(e string, m map[string]string, b bool) {
+// emitSql renders SQL in dialect d from e applying m, with placeholders in
+// style p if b is true.
+func emitSql(e string, m map[string]string, b bool, d string, p string) {
 	r := strings.NewReader(e)
 	w := new(bytes.Buffer)
 
+	dialect, err := espressopp.GetDialect(d)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	interpreter := espressopp.NewEspressoppInterpreter()
-	codeGenerator := espressopp.NewSqlCodeGenerator()
+	codeGenerator := espressopp.NewSqlCodeGeneratorWithDialect(dialect)
 	codeGenerator.RenderingOptions.FieldsWithDefault(m)
 
 	if b {
+		style, err := espressopp.GetPlaceholderStyle(p)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 		codeGenerator.RenderingOptions.EnableNamedParams()
+		codeGenerator.RenderingOptions.SetPlaceholderStyle(style)
 	}
 
 	if err := interpreter.Accept(codeGenerator, r, w); err != nil {
@@ -45,18 +60,52 @@ func emitSql(e string, m map[string]string, b bool) {
 	fmt.Println(w.String())
 
 	if b {
-		values, _ := codeGenerator.RenderingOptions.GetNamedParamValues()
-		if len(values) > 0 {
+		params, _ := codeGenerator.RenderingOptions.GetNamedParams()
+		if len(params) > 0 {
 			fmt.Println()
 			fmt.Println("Named Parameters")
 			fmt.Println("================")
-			for k, v := range values {
-				fmt.Printf("%s: %s\n", k, v)
+			for _, p := range params {
+				fmt.Printf("%s: %v\n", p.Name, p.Value)
 			}
 		}
 	}
 }
 
+// emitMongo renders a MongoDB filter from e applying m.
+func emitMongo(e string, m map[string]string) {
+	r := strings.NewReader(e)
+	w := new(bytes.Buffer)
+
+	interpreter := espressopp.NewEspressoppInterpreter()
+	codeGenerator := espressopp.NewMongoCodeGenerator()
+	codeGenerator.RenderingOptions.FieldsWithDefault(m)
+
+	if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(w.String())
+}
+
+// emitElasticsearch renders an Elasticsearch query from e applying m.
+func emitElasticsearch(e string, m map[string]string) {
+	r := strings.NewReader(e)
+	w := new(bytes.Buffer)
+
+	interpreter := espressopp.NewEspressoppInterpreter()
+	codeGenerator := espressopp.NewElasticsearchCodeGenerator()
+	codeGenerator.RenderingOptions.FieldsWithDefault(m)
+
+	if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(w.String())
+}
+
 // main is the program's entry point.
 func main() {
 	ctx := kong.Parse(&cli,
@@ -72,7 +121,11 @@ func main() {
 	case "generate <target> <expression>", "generate <target> <expression> <fieldmap>":
 		switch strings.ToLower(cli.Generate.Target) {
 		case "sql":
-			emitSql(cli.Generate.Expression, cli.Generate.FieldMap, cli.Generate.EnableNamedParams)
+			emitSql(cli.Generate.Expression, cli.Generate.FieldMap, cli.Generate.EnableNamedParams, cli.Generate.Dialect, cli.Generate.Placeholders)
+		case "mongo":
+			emitMongo(cli.Generate.Expression, cli.Generate.FieldMap)
+		case "elasticsearch":
+			emitElasticsearch(cli.Generate.Expression, cli.Generate.FieldMap)
 		default:
 			fmt.Println(fmt.Errorf("Target '%v' not supported.", cli.Generate.Target))
 		}
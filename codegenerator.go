@@ -18,4 +18,9 @@ type CodeGenerator interface {
 	// back the grammar, which is then used to produce the native query into
 	// the specified writer.
 	Visit(Interpreter, io.Reader, io.Writer) error
+
+	// Generate produces the native query from the specified grammar. Unlike
+	// Visit, Generate does not alter the code generator, which can therefore
+	// be shared by multiple goroutines.
+	Generate(*Grammar) (*RenderResult, error)
 }
@@ -0,0 +1,979 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pluralize "github.com/gertd/go-pluralize"
+	"github.com/pkg/errors"
+)
+
+// Binder binds the specified value to a new named parameter and returns the
+// placeholder of the parameter.
+type Binder func(interface{}) string
+
+// Dialect is the interface implemented by any SQL dialect, i.e. the set of rules
+// SqlCodeGenerator follows to render those constructs that are not supported
+// uniformly across database engines.
+type Dialect interface {
+	// Name returns the name of the dialect.
+	Name() string
+
+	// QuoteIdent quotes the specified identifier if it is a reserved word or
+	// it contains characters that are not allowed in unquoted identifiers.
+	// Qualified identifiers like table.column are quoted part by part.
+	QuoteIdent(string) string
+
+	// Bool renders the specified Boolean value.
+	Bool(bool) string
+
+	// QuoteString renders the specified string as a string literal, escaping
+	// any character that would otherwise terminate or alter the literal.
+	QuoteString(string) string
+
+	// EscapeLike escapes the wildcards in the specified string, as well as the
+	// escape character itself, so that they match literally in LIKE patterns.
+	EscapeLike(string) string
+
+	// Placeholder renders the placeholder of the named parameter with the
+	// specified name and position, where the first position is 1.
+	Placeholder(string, int) string
+
+	// DateLiteral renders the specified quoted date as a date literal.
+	DateLiteral(string) string
+
+	// TimeLiteral renders the specified quoted time as a time literal.
+	TimeLiteral(string) string
+
+	// DateTimeLiteral renders the specified quoted timestamp as a timestamp
+	// literal.
+	DateTimeLiteral(string) string
+
+	// Interval renders the specified duration as an interval value, or returns
+	// an error if the dialect does not support interval values. If the Binder
+	// is not nil, then the duration is bound to named parameters instead of
+	// being rendered inline.
+	Interval(*Duration, Binder) (string, error)
+
+	// DateAdd renders the addition of the specified duration to the specified
+	// date expression. If the Boolean argument is true, then the duration is
+	// subtracted instead. If the Binder is not nil, then the duration is bound
+	// to named parameters instead of being rendered inline.
+	DateAdd(string, *Duration, bool, Binder) (string, error)
+
+	// Like renders the match of the specified expression against the
+	// specified LIKE pattern, whose literal parts were escaped by EscapeLike.
+	Like(string, string) string
+
+	// ILike renders the case-insensitive match of the specified expression
+	// against the specified LIKE pattern, whose literal parts were escaped by
+	// EscapeLike.
+	ILike(string, string) string
+
+	// JsonPath renders the extraction of the value at the specified path of
+	// keys from the specified JSON column.
+	JsonPath(string, []string) string
+
+	// Regexp renders the match of the specified expression against the
+	// specified regular expression in Go syntax, or returns an error if the
+	// dialect does not support the regular expression. If the Binder is not
+	// nil, then the regular expression is bound to a named parameter instead
+	// of being rendered inline.
+	Regexp(string, string, Binder) (string, error)
+
+	// ArrayPredicate renders the test of whether any element of the specified
+	// array expression, or every element if the Boolean argument is true,
+	// satisfies the specified operator with the specified operands. The
+	// operator is one of has, eq, neq, gt, gte, lt, lte, or in, where has is
+	// the same as eq.
+	ArrayPredicate(string, bool, string, []string) string
+
+	// Function renders the call of the builtin scalar function with the
+	// specified name and arguments, or returns an error if the dialect does
+	// not support the function.
+	Function(string, []string) (string, error)
+}
+
+// sqlFunction renders the call of a builtin scalar function with the specified
+// arguments.
+type sqlFunction func([]string) string
+
+var (
+	// sqlOperators maps the comparison operators to their SQL counterparts.
+	sqlOperators = map[string]string{
+		"has": "=",
+		"eq":  "=",
+		"neq": "<>",
+		"gt":  ">",
+		"gte": ">=",
+		"lt":  "<",
+		"lte": "<=",
+	}
+
+	// genericFunctions maps the builtin scalar functions to their rendering in
+	// standard SQL.
+	genericFunctions = map[string]sqlFunction{
+		"lower":    call("LOWER"),
+		"upper":    call("UPPER"),
+		"trim":     call("TRIM"),
+		"length":   call("CHAR_LENGTH"),
+		"abs":      call("ABS"),
+		"round":    call("ROUND"),
+		"floor":    call("FLOOR"),
+		"ceil":     call("CEIL"),
+		"coalesce": call("COALESCE"),
+		"year":     extract("YEAR"),
+		"month":    extract("MONTH"),
+		"day":      extract("DAY"),
+		"hour":     extract("HOUR"),
+	}
+
+	// postgresFunctions maps the builtin scalar functions that PostgreSQL
+	// renders differently from standard SQL.
+	postgresFunctions = map[string]sqlFunction{
+		"dayofweek": func(args []string) string {
+			return fmt.Sprintf("(EXTRACT(DOW FROM %s) + 1)", args[0])
+		},
+	}
+
+	// mySqlFunctions maps the builtin scalar functions that MySQL renders
+	// differently from standard SQL.
+	mySqlFunctions = map[string]sqlFunction{
+		"dayofweek": call("DAYOFWEEK"),
+	}
+
+	// sqliteFunctions maps the builtin scalar functions that SQLite renders
+	// differently from standard SQL.
+	sqliteFunctions = map[string]sqlFunction{
+		"length":    call("LENGTH"),
+		"year":      strftime("%Y", 0),
+		"month":     strftime("%m", 0),
+		"day":       strftime("%d", 0),
+		"hour":      strftime("%H", 0),
+		"dayofweek": strftime("%w", 1),
+	}
+
+	// sqlServerFunctions maps the builtin scalar functions that SQL Server
+	// renders differently from standard SQL.
+	sqlServerFunctions = map[string]sqlFunction{
+		"length": call("LEN"),
+		"ceil":   call("CEILING"),
+		"round": func(args []string) string {
+			if len(args) == 1 {
+				args = append(args, "0")
+			}
+			return call("ROUND")(args)
+		},
+		"year":      datePart("YEAR"),
+		"month":     datePart("MONTH"),
+		"day":       datePart("DAY"),
+		"hour":      datePart("HOUR"),
+		"dayofweek": datePart("WEEKDAY"),
+	}
+
+	// oracleFunctions maps the builtin scalar functions that Oracle renders
+	// differently from standard SQL.
+	oracleFunctions = map[string]sqlFunction{
+		"length": call("LENGTH"),
+		"dayofweek": func(args []string) string {
+			return fmt.Sprintf("TO_NUMBER(TO_CHAR(%s, 'D'))", args[0])
+		},
+	}
+
+	// likeEscaper escapes LIKE wildcards with \.
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	// unquotedIdent matches identifiers that do not need to be quoted.
+	unquotedIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// reservedWords contains the keywords that cannot be used as unquoted
+	// identifiers in most dialects.
+	reservedWords = map[string]bool{
+		"ALL": true, "ALTER": true, "AND": true, "AS": true, "ASC": true,
+		"BETWEEN": true, "BY": true, "CASE": true, "CHECK": true, "COLUMN": true,
+		"CREATE": true, "CROSS": true, "DEFAULT": true, "DELETE": true, "DESC": true,
+		"DISTINCT": true, "DROP": true, "ELSE": true, "END": true, "EXISTS": true,
+		"FALSE": true, "FOREIGN": true, "FROM": true, "FULL": true, "GRANT": true,
+		"GROUP": true, "HAVING": true, "IN": true, "INDEX": true, "INNER": true,
+		"INSERT": true, "INTO": true, "IS": true, "JOIN": true, "KEY": true,
+		"LEFT": true, "LIKE": true, "LIMIT": true, "NOT": true, "NULL": true,
+		"OFFSET": true, "ON": true, "OR": true, "ORDER": true, "OUTER": true,
+		"PRIMARY": true, "REFERENCES": true, "RIGHT": true, "SELECT": true, "SET": true,
+		"TABLE": true, "THEN": true, "TO": true, "TRUE": true, "UNION": true,
+		"UNIQUE": true, "UPDATE": true, "USER": true, "VALUES": true, "WHEN": true,
+		"WHERE": true, "WITH": true,
+	}
+)
+
+// GetDialect returns the built-in dialect with the specified name.
+func GetDialect(name string) (Dialect, error) {
+	var d Dialect
+
+	switch strings.ToLower(name) {
+	case "", "generic":
+		d = NewGenericDialect()
+	case "postgres", "postgresql":
+		d = NewPostgresDialect()
+	case "mysql":
+		d = NewMySqlDialect()
+	case "sqlite":
+		d = NewSqliteDialect()
+	case "sqlserver", "mssql":
+		d = NewSqlServerDialect()
+	case "oracle":
+		d = NewOracleDialect()
+	default:
+		return nil, errors.Errorf("dialect %v not supported", name)
+	}
+
+	return d, nil
+}
+
+// quoteIdent quotes the parts of the qualified identifier s that need to be
+// quoted with the specified opening and closing characters.
+func quoteIdent(s string, open string, close string) string {
+	parts := strings.Split(s, ".")
+
+	for i, p := range parts {
+		if !unquotedIdent.MatchString(p) || reservedWords[strings.ToUpper(p)] {
+			parts[i] = open + strings.ReplaceAll(p, close, close+close) + close
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// bindInt renders v, or binds it with b if b is not nil.
+func bindInt(v int, b Binder) string {
+	if b != nil {
+		return b(int64(v))
+	}
+
+	return strconv.Itoa(v)
+}
+
+// bindAmount renders the amount of p, including fractional seconds, or binds
+// it with b if b is not nil.
+func bindAmount(p durationPart, b Binder) string {
+	if p.micros == 0 {
+		return bindInt(p.value, b)
+	} else if b != nil {
+		v, _ := strconv.ParseFloat(p.amount(), 64)
+		return b(v)
+	}
+
+	return p.amount()
+}
+
+// GenericDialect is the Dialect implementation that renders a generic flavor of
+// SQL. It is the default dialect of SqlCodeGenerator and is meant to be embedded
+// by dialects that only differ in a few constructs.
+type GenericDialect struct{}
+
+// NewGenericDialect creates a new instance of GenericDialect.
+func NewGenericDialect() *GenericDialect {
+	return &GenericDialect{}
+}
+
+// Name returns the name of d.
+func (d *GenericDialect) Name() string {
+	return "generic"
+}
+
+// QuoteIdent quotes s with double quotes if needed.
+func (d *GenericDialect) QuoteIdent(s string) string {
+	return quoteIdent(s, `"`, `"`)
+}
+
+// Bool renders b as either 1 or 0.
+func (d *GenericDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+// QuoteString renders s as a string literal enclosed in single quotes, where
+// embedded single quotes are doubled.
+func (d *GenericDialect) QuoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// EscapeLike escapes %, _, and \ in s with \.
+func (d *GenericDialect) EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// Placeholder renders the placeholder of the named parameter n as :n.
+func (d *GenericDialect) Placeholder(n string, pos int) string {
+	return ":" + n
+}
+
+// DateLiteral renders s as DATE s.
+func (d *GenericDialect) DateLiteral(s string) string {
+	return "DATE " + s
+}
+
+// TimeLiteral renders s as TIME s.
+func (d *GenericDialect) TimeLiteral(s string) string {
+	return "TIME " + s
+}
+
+// DateTimeLiteral renders s as TIMESTAMP s.
+func (d *GenericDialect) DateTimeLiteral(s string) string {
+	return "TIMESTAMP " + s
+}
+
+// Interval renders du as a single interval value, e.g. INTERVAL '1 DAY 2 HOURS',
+// or as CAST(:P1 AS INTERVAL) if b is not nil. Seconds can be fractional.
+func (d *GenericDialect) Interval(du *Duration, b Binder) (string, error) {
+	parts := du.parts(true)
+
+	p := pluralize.NewClient()
+	items := make([]string, len(parts))
+	for i, part := range parts {
+		if part.micros != 0 {
+			items[i] = part.amount() + " " + p.Plural(part.unit)
+		} else {
+			items[i] = p.Pluralize(part.unit, part.value, true)
+		}
+	}
+
+	if b != nil {
+		return fmt.Sprintf("CAST(%s AS INTERVAL)", b(strings.Join(items, " "))), nil
+	}
+
+	return fmt.Sprintf("INTERVAL '%s'", strings.Join(items, " ")), nil
+}
+
+// DateAdd renders the addition of du to e as e + interval.
+func (d *GenericDialect) DateAdd(e string, du *Duration, sub bool, b Binder) (string, error) {
+	i, err := d.Interval(du, b)
+	if err != nil {
+		return "", err
+	}
+
+	op := "+"
+	if sub {
+		op = "-"
+	}
+
+	return fmt.Sprintf("%s %s %s", e, op, i), nil
+}
+
+// Like renders the match of e against the LIKE pattern p, with \ as the escape
+// character.
+func (d *GenericDialect) Like(e string, p string) string {
+	return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, e, p)
+}
+
+// ILike renders the case-insensitive match of e against the LIKE pattern p by
+// lowering the case of both.
+func (d *GenericDialect) ILike(e string, p string) string {
+	return d.Like("LOWER("+e+")", "LOWER("+p+")")
+}
+
+// JsonPath renders the extraction of the value at keys from column c with
+// JSON_VALUE, e.g. JSON_VALUE(data, '$.address.city').
+func (d *GenericDialect) JsonPath(c string, keys []string) string {
+	return fmt.Sprintf("JSON_VALUE(%s, %s)", c, d.QuoteString("$."+strings.Join(keys, ".")))
+}
+
+// Regexp returns an error since there is no standard regular expression
+// operator.
+func (d *GenericDialect) Regexp(e string, p string, b Binder) (string, error) {
+	return "", errors.Errorf("%s does not support regular expressions", d.Name())
+}
+
+// ArrayPredicate renders the test on the elements of array a as a subquery on
+// UNNEST, e.g. EXISTS (SELECT 1 FROM UNNEST(a) AS e(elem) WHERE elem = 'x').
+func (d *GenericDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	return arrayExists(fmt.Sprintf("UNNEST(%s) AS e(elem)", a), "elem", all, op, xs)
+}
+
+// arrayExists renders the test on the elements of an array as a subquery on
+// from, where elem is the column holding the elements. Tests on all elements
+// are rendered as the absence of elements that do not pass the test.
+func arrayExists(from string, elem string, all bool, op string, xs []string) string {
+	var cond string
+	if op == "in" {
+		cond = fmt.Sprintf("%s IN (%s)", elem, strings.Join(xs, ", "))
+	} else {
+		cond = fmt.Sprintf("%s %s %s", elem, sqlOperators[op], xs[0])
+	}
+
+	if all {
+		return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE NOT (%s))", from, cond)
+	}
+
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)", from, cond)
+}
+
+// Function renders the call of the builtin scalar function n in standard SQL.
+// The day of the week is not supported since there is no standard way to get it.
+func (d *GenericDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args)
+}
+
+// renderFunction renders the call of the builtin scalar function n with the
+// first sqlFunction found in fs, or with its standard SQL counterpart. It
+// returns an error that mentions engine if n is not supported.
+func renderFunction(engine string, n string, args []string, fs ...map[string]sqlFunction) (string, error) {
+	for _, m := range append(fs, genericFunctions) {
+		if f, ok := m[n]; ok {
+			return f(args), nil
+		}
+	}
+
+	return "", errors.Errorf("%s does not support function %s", engine, n)
+}
+
+// call returns the sqlFunction that renders a call of the SQL function n, e.g.
+// LOWER(name).
+func call(n string) sqlFunction {
+	return func(args []string) string {
+		return fmt.Sprintf("%s(%s)", n, strings.Join(args, ", "))
+	}
+}
+
+// extract returns the sqlFunction that renders the extraction of field f from
+// a date, e.g. EXTRACT(YEAR FROM created).
+func extract(f string) sqlFunction {
+	return func(args []string) string {
+		return fmt.Sprintf("EXTRACT(%s FROM %s)", f, args[0])
+	}
+}
+
+// datePart returns the sqlFunction that renders the extraction of part p from
+// a date with DATEPART, e.g. DATEPART(YEAR, created).
+func datePart(p string) sqlFunction {
+	return func(args []string) string {
+		return fmt.Sprintf("DATEPART(%s, %s)", p, args[0])
+	}
+}
+
+// strftime returns the sqlFunction that renders the extraction of a date part
+// with strftime and format f, plus offset, e.g. CAST(strftime('%Y', created) AS
+// INTEGER).
+func strftime(f string, offset int) sqlFunction {
+	return func(args []string) string {
+		s := fmt.Sprintf("CAST(strftime('%s', %s) AS INTEGER)", f, args[0])
+		if offset != 0 {
+			s = fmt.Sprintf("(%s + %d)", s, offset)
+		}
+		return s
+	}
+}
+
+// regexpOperand validates the regular expression p against the features the
+// engine does not support and returns either its quoted value or, if b is not
+// nil, the placeholder of the named parameter it is bound to.
+func regexpOperand(p string, unsupported regexpFeature, engine string, quote func(string) string, b Binder) (string, error) {
+	if err := validateRegexp(p, unsupported, engine); err != nil {
+		return "", err
+	}
+
+	if b != nil {
+		return b(p), nil
+	}
+
+	return quote(p), nil
+}
+
+// dateAddParts renders the addition of parts to e as a sequence of additions,
+// one per duration component, each rendered by f with a positive value.
+// Negative components are subtracted instead, and weeks are converted into
+// days if weeks is false.
+func dateAddParts(e string, parts []durationPart, sub bool, weeks bool, f func(durationPart) string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(e)
+
+	for _, p := range parts {
+		if p.unit == "WEEK" && !weeks {
+			p = durationPart{p.value * 7, "DAY", 0}
+		}
+
+		p, neg := p.abs()
+		op := "+"
+		if sub != neg {
+			op = "-"
+		}
+		sb.WriteString(fmt.Sprintf(" %s %s", op, f(p)))
+	}
+
+	return sb.String(), nil
+}
+
+// PostgresDialect is the Dialect implementation for PostgreSQL.
+type PostgresDialect struct {
+	GenericDialect
+}
+
+// NewPostgresDialect creates a new instance of PostgresDialect.
+func NewPostgresDialect() *PostgresDialect {
+	return &PostgresDialect{}
+}
+
+// Name returns the name of d.
+func (d *PostgresDialect) Name() string {
+	return "postgres"
+}
+
+// Bool renders b as either TRUE or FALSE.
+func (d *PostgresDialect) Bool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// ILike renders the case-insensitive match of e against the LIKE pattern p as
+// ILIKE.
+func (d *PostgresDialect) ILike(e string, p string) string {
+	return fmt.Sprintf(`%s ILIKE %s ESCAPE '\'`, e, p)
+}
+
+// JsonPath renders the extraction of the value at keys from column c with ->
+// and ->>, e.g. data->'address'->>'city'.
+func (d *PostgresDialect) JsonPath(c string, keys []string) string {
+	var sb strings.Builder
+
+	sb.WriteString(c)
+	for i, k := range keys {
+		if i < len(keys)-1 {
+			sb.WriteString("->")
+		} else {
+			sb.WriteString("->>")
+		}
+		sb.WriteString(d.QuoteString(k))
+	}
+
+	return sb.String()
+}
+
+// Regexp renders the match of e against the regular expression p with ~. Word
+// boundaries are rejected since \b denotes a backspace in Postgres.
+func (d *PostgresDialect) Regexp(e string, p string, b Binder) (string, error) {
+	r, err := regexpOperand(p, wordBoundaries|lineAnchors|caseFolding|namedGroups, d.Name(), d.QuoteString, b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s ~ %s", e, r), nil
+}
+
+// ArrayPredicate renders the test on the elements of array a with the array
+// operators, i.e. @> for has, && and <@ for any in and all in, whereas any
+// other test is rendered with ANY or ALL, e.g. 'x' = ANY(a).
+func (d *PostgresDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	switch {
+	case op == "has":
+		return fmt.Sprintf("%s @> ARRAY[%s]", a, xs[0])
+	case op == "in" && all:
+		return fmt.Sprintf("%s <@ ARRAY[%s]", a, strings.Join(xs, ", "))
+	case op == "in":
+		return fmt.Sprintf("%s && ARRAY[%s]", a, strings.Join(xs, ", "))
+	}
+
+	q := "ANY"
+	if all {
+		q = "ALL"
+	}
+
+	return fmt.Sprintf("%s %s %s(%s)", xs[0], sqlOperators[mirrorOp(op)], q, a)
+}
+
+// Function renders the call of the builtin scalar function n, where the day of
+// the week is rendered with EXTRACT(DOW ...) shifted to start from 1 on Sunday.
+func (d *PostgresDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args, postgresFunctions)
+}
+
+// Placeholder renders the placeholder at position pos as $pos.
+func (d *PostgresDialect) Placeholder(n string, pos int) string {
+	return fmt.Sprintf("$%d", pos)
+}
+
+// MySqlDialect is the Dialect implementation for MySQL and MariaDB.
+type MySqlDialect struct {
+	GenericDialect
+}
+
+// NewMySqlDialect creates a new instance of MySqlDialect.
+func NewMySqlDialect() *MySqlDialect {
+	return &MySqlDialect{}
+}
+
+// Name returns the name of d.
+func (d *MySqlDialect) Name() string {
+	return "mysql"
+}
+
+// QuoteIdent quotes s with backticks if needed.
+func (d *MySqlDialect) QuoteIdent(s string) string {
+	return quoteIdent(s, "`", "`")
+}
+
+// Bool renders b as either TRUE or FALSE.
+func (d *MySqlDialect) Bool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// QuoteString renders s as a string literal enclosed in single quotes, where
+// embedded single quotes are doubled and backslashes are escaped.
+func (d *MySqlDialect) QuoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+// Like renders the match of e against the LIKE pattern p. The ESCAPE clause is
+// omitted since \ is the default escape character in MySQL.
+func (d *MySqlDialect) Like(e string, p string) string {
+	return fmt.Sprintf("%s LIKE %s", e, p)
+}
+
+// ILike renders the case-insensitive match of e against the LIKE pattern p by
+// lowering the case of both.
+func (d *MySqlDialect) ILike(e string, p string) string {
+	return d.Like("LOWER("+e+")", "LOWER("+p+")")
+}
+
+// JsonPath renders the extraction of the value at keys from column c with
+// JSON_EXTRACT, e.g. JSON_EXTRACT(data, '$.address.city').
+func (d *MySqlDialect) JsonPath(c string, keys []string) string {
+	return fmt.Sprintf("JSON_EXTRACT(%s, %s)", c, d.QuoteString("$."+strings.Join(keys, ".")))
+}
+
+// Regexp renders the match of e against the regular expression p with REGEXP.
+func (d *MySqlDialect) Regexp(e string, p string, b Binder) (string, error) {
+	r, err := regexpOperand(p, namedGroups, d.Name(), d.QuoteString, b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s REGEXP %s", e, r), nil
+}
+
+// ArrayPredicate renders the test on the elements of JSON array a with MEMBER
+// OF for has, whereas any other test is rendered as a subquery on JSON_TABLE.
+func (d *MySqlDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	if op == "has" {
+		return fmt.Sprintf("%s MEMBER OF(%s)", xs[0], a)
+	}
+
+	from := fmt.Sprintf("JSON_TABLE(%s, '$[*]' COLUMNS (elem JSON PATH '$')) AS e", a)
+	return arrayExists(from, "elem", all, op, xs)
+}
+
+// Function renders the call of the builtin scalar function n, where the day of
+// the week is rendered with DAYOFWEEK.
+func (d *MySqlDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args, mySqlFunctions)
+}
+
+// Placeholder renders any placeholder as ?.
+func (d *MySqlDialect) Placeholder(n string, pos int) string {
+	return "?"
+}
+
+// Interval renders du as an interval value, e.g. INTERVAL 2 HOUR. MySQL only
+// supports intervals in date arithmetic, and each interval can have just one
+// unit, where fractional seconds are expressed in microseconds.
+func (d *MySqlDialect) Interval(du *Duration, b Binder) (string, error) {
+	parts := du.parts(false)
+	if len(parts) != 1 {
+		return "", errors.Errorf("%s does not support intervals with multiple units", d.Name())
+	}
+
+	return fmt.Sprintf("INTERVAL %s %s", bindInt(parts[0].value, b), parts[0].unit), nil
+}
+
+// DateAdd renders the addition of du to e as a sequence of intervals, e.g.
+// e + INTERVAL 1 DAY + INTERVAL 2 HOUR.
+func (d *MySqlDialect) DateAdd(e string, du *Duration, sub bool, b Binder) (string, error) {
+	return dateAddParts(e, du.parts(false), sub, true, func(p durationPart) string {
+		return fmt.Sprintf("INTERVAL %s %s", bindInt(p.value, b), p.unit)
+	})
+}
+
+// SqliteDialect is the Dialect implementation for SQLite.
+type SqliteDialect struct {
+	GenericDialect
+}
+
+// NewSqliteDialect creates a new instance of SqliteDialect.
+func NewSqliteDialect() *SqliteDialect {
+	return &SqliteDialect{}
+}
+
+// Name returns the name of d.
+func (d *SqliteDialect) Name() string {
+	return "sqlite"
+}
+
+// Placeholder renders any placeholder as ?.
+func (d *SqliteDialect) Placeholder(n string, pos int) string {
+	return "?"
+}
+
+// DateLiteral renders s as date(s).
+func (d *SqliteDialect) DateLiteral(s string) string {
+	return fmt.Sprintf("date(%s)", s)
+}
+
+// TimeLiteral renders s as time(s).
+func (d *SqliteDialect) TimeLiteral(s string) string {
+	return fmt.Sprintf("time(%s)", s)
+}
+
+// DateTimeLiteral renders s as datetime(s).
+func (d *SqliteDialect) DateTimeLiteral(s string) string {
+	return fmt.Sprintf("datetime(%s)", s)
+}
+
+// JsonPath renders the extraction of the value at keys from column c with
+// JSON_EXTRACT, e.g. JSON_EXTRACT(data, '$.address.city').
+func (d *SqliteDialect) JsonPath(c string, keys []string) string {
+	return fmt.Sprintf("JSON_EXTRACT(%s, %s)", c, d.QuoteString("$."+strings.Join(keys, ".")))
+}
+
+// Regexp renders the match of e against the regular expression p with REGEXP,
+// which requires the application to register a regexp function. Only the
+// features common to most implementations are supported.
+func (d *SqliteDialect) Regexp(e string, p string, b Binder) (string, error) {
+	r, err := regexpOperand(p, wordBoundaries|lineAnchors|nonGreedy|caseFolding|namedGroups, d.Name(), d.QuoteString, b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s REGEXP %s", e, r), nil
+}
+
+// ArrayPredicate renders the test on the elements of JSON array a as a subquery
+// on json_each, e.g. EXISTS (SELECT 1 FROM json_each(a) WHERE value = 'x').
+func (d *SqliteDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	return arrayExists(fmt.Sprintf("json_each(%s)", a), "value", all, op, xs)
+}
+
+// Function renders the call of the builtin scalar function n, where date parts
+// are extracted with strftime. Both floor and ceil require SQLite to be built
+// with math functions.
+func (d *SqliteDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args, sqliteFunctions)
+}
+
+// Interval always returns an error since SQLite does not support intervals.
+func (d *SqliteDialect) Interval(du *Duration, b Binder) (string, error) {
+	return "", errors.Errorf("%s does not support interval values", d.Name())
+}
+
+// DateAdd renders the addition of du to e with date and time modifiers, e.g.
+// datetime(e, '+1 days', '+2 hours'). If b is not nil, then each modifier is
+// bound to a named parameter.
+func (d *SqliteDialect) DateAdd(e string, du *Duration, sub bool, b Binder) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("datetime(")
+	sb.WriteString(e)
+
+	for _, p := range du.parts(true) {
+		if p.unit == "WEEK" {
+			p = durationPart{p.value * 7, "DAY", 0}
+		}
+
+		p, neg := p.abs()
+		sign := "+"
+		if sub != neg {
+			sign = "-"
+		}
+		m := fmt.Sprintf("%s%s %ss", sign, p.amount(), strings.ToLower(p.unit))
+		if b != nil {
+			m = b(m)
+		} else {
+			m = d.QuoteString(m)
+		}
+		sb.WriteString(", " + m)
+	}
+
+	sb.WriteString(")")
+
+	return sb.String(), nil
+}
+
+// SqlServerDialect is the Dialect implementation for Microsoft SQL Server.
+type SqlServerDialect struct {
+	GenericDialect
+}
+
+// NewSqlServerDialect creates a new instance of SqlServerDialect.
+func NewSqlServerDialect() *SqlServerDialect {
+	return &SqlServerDialect{}
+}
+
+// Name returns the name of d.
+func (d *SqlServerDialect) Name() string {
+	return "sqlserver"
+}
+
+// QuoteIdent quotes s with square brackets if needed.
+func (d *SqlServerDialect) QuoteIdent(s string) string {
+	return quoteIdent(s, "[", "]")
+}
+
+// EscapeLike escapes %, _, [, and \ in s with \.
+func (d *SqlServerDialect) EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `[`, `\[`).Replace(s)
+}
+
+// Placeholder renders the placeholder of the named parameter n as @n.
+func (d *SqlServerDialect) Placeholder(n string, pos int) string {
+	return "@" + n
+}
+
+// DateLiteral renders s as CAST(s AS DATE).
+func (d *SqlServerDialect) DateLiteral(s string) string {
+	return fmt.Sprintf("CAST(%s AS DATE)", s)
+}
+
+// TimeLiteral renders s as CAST(s AS TIME).
+func (d *SqlServerDialect) TimeLiteral(s string) string {
+	return fmt.Sprintf("CAST(%s AS TIME)", s)
+}
+
+// DateTimeLiteral renders s as CAST(s AS DATETIME2).
+func (d *SqlServerDialect) DateTimeLiteral(s string) string {
+	return fmt.Sprintf("CAST(%s AS DATETIME2)", s)
+}
+
+// Regexp returns an error since SQL Server has no regular expression operator.
+func (d *SqlServerDialect) Regexp(e string, p string, b Binder) (string, error) {
+	return "", errors.Errorf("%s does not support regular expressions", d.Name())
+}
+
+// ArrayPredicate renders the test on the elements of JSON array a as a subquery
+// on OPENJSON, e.g. EXISTS (SELECT 1 FROM OPENJSON(a) WHERE value = 'x').
+func (d *SqlServerDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	return arrayExists(fmt.Sprintf("OPENJSON(%s)", a), "value", all, op, xs)
+}
+
+// Function renders the call of the builtin scalar function n, where date parts
+// are extracted with DATEPART. The day of the week depends on DATEFIRST.
+func (d *SqlServerDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args, sqlServerFunctions)
+}
+
+// Interval always returns an error since SQL Server does not support intervals.
+func (d *SqlServerDialect) Interval(du *Duration, b Binder) (string, error) {
+	return "", errors.Errorf("%s does not support interval values", d.Name())
+}
+
+// DateAdd renders the addition of du to e with nested DATEADD calls, e.g.
+// DATEADD(HOUR, 2, DATEADD(DAY, 1, e)), where fractional seconds are expressed
+// in microseconds.
+func (d *SqlServerDialect) DateAdd(e string, du *Duration, sub bool, b Binder) (string, error) {
+	s := e
+	for _, p := range du.parts(false) {
+		v := p.value
+		if sub {
+			v = -v
+		}
+		s = fmt.Sprintf("DATEADD(%s, %s, %s)", p.unit, bindInt(v, b), s)
+	}
+
+	return s, nil
+}
+
+// OracleDialect is the Dialect implementation for Oracle Database.
+type OracleDialect struct {
+	GenericDialect
+}
+
+// NewOracleDialect creates a new instance of OracleDialect.
+func NewOracleDialect() *OracleDialect {
+	return &OracleDialect{}
+}
+
+// Name returns the name of d.
+func (d *OracleDialect) Name() string {
+	return "oracle"
+}
+
+// TimeLiteral returns s as is since Oracle does not support time literals.
+func (d *OracleDialect) TimeLiteral(s string) string {
+	return s
+}
+
+// Regexp renders the match of e against the regular expression p with
+// REGEXP_LIKE.
+func (d *OracleDialect) Regexp(e string, p string, b Binder) (string, error) {
+	r, err := regexpOperand(p, wordBoundaries|lineAnchors|caseFolding|namedGroups, d.Name(), d.QuoteString, b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("REGEXP_LIKE(%s, %s)", e, r), nil
+}
+
+// ArrayPredicate renders the test on the elements of JSON array a as a subquery
+// on JSON_TABLE.
+func (d *OracleDialect) ArrayPredicate(a string, all bool, op string, xs []string) string {
+	from := fmt.Sprintf("JSON_TABLE(%s, '$[*]' COLUMNS (elem VARCHAR2(4000) PATH '$'))", a)
+	return arrayExists(from, "elem", all, op, xs)
+}
+
+// Function renders the call of the builtin scalar function n, where the day of
+// the week is rendered with TO_CHAR and depends on NLS_TERRITORY.
+func (d *OracleDialect) Function(n string, args []string) (string, error) {
+	return renderFunction(d.Name(), n, args, oracleFunctions)
+}
+
+// Interval renders du as an interval value, e.g. INTERVAL '2' HOUR. Oracle does
+// not support intervals with multiple units other than in date arithmetic.
+func (d *OracleDialect) Interval(du *Duration, b Binder) (string, error) {
+	parts := du.parts(true)
+	if len(parts) != 1 {
+		return "", errors.Errorf("%s does not support intervals with multiple units", d.Name())
+	}
+
+	return d.interval(parts[0], b), nil
+}
+
+// DateAdd renders the addition of du to e as a sequence of intervals, e.g.
+// e + INTERVAL '1' DAY + INTERVAL '2' HOUR.
+func (d *OracleDialect) DateAdd(e string, du *Duration, sub bool, b Binder) (string, error) {
+	return dateAddParts(e, du.parts(true), sub, false, func(p durationPart) string {
+		return d.interval(p, b)
+	})
+}
+
+// interval renders p as an interval value. If b is not nil, then the value of
+// p is bound to a named parameter and the interval is rendered with either
+// NUMTOYMINTERVAL or NUMTODSINTERVAL, e.g. NUMTODSINTERVAL(:P1, 'HOUR').
+func (d *OracleDialect) interval(p durationPart, b Binder) string {
+	if p.unit == "WEEK" {
+		p = durationPart{p.value * 7, "DAY", 0}
+	}
+
+	if b == nil {
+		return fmt.Sprintf("INTERVAL '%s' %s", p.amount(), p.unit)
+	}
+
+	f := "NUMTODSINTERVAL"
+	if p.unit == "YEAR" || p.unit == "MONTH" {
+		f = "NUMTOYMINTERVAL"
+	}
+
+	return fmt.Sprintf("%s(%s, '%s')", f, bindAmount(p, b), p.unit)
+}
@@ -0,0 +1,110 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestGenerateSqlDialects tests the generation of SQL in the built-in dialects.
+func TestGenerateSqlDialects(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for name, items := range getDialectTestDataItems() {
+		dialect, err := GetDialect(name)
+		if err != nil {
+			t.Fatalf("Dialect '%v' : FAILED, %v", name, err)
+		}
+
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+		codeGenerator.RenderingOptions.AddFieldProps("order", &FieldProps{Filterable: true})
+		codeGenerator.RenderingOptions.AddPathProps("json", &PathProps{JsonColumn: "data"})
+		codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+		for _, item := range items {
+			r := strings.NewReader(item.input)
+			w := new(bytes.Buffer)
+			err := interpreter.Accept(codeGenerator, r, w)
+
+			result := w.String()
+
+			if item.hasError {
+				if err == nil {
+					t.Errorf("Dialect '%v' with input '%v' : FAILED, expected an error but got '%v'", name, item.input, result)
+				} else {
+					t.Logf("Dialect '%v' with input '%v' : PASSED, expected an error and got '%v'", name, item.input, err)
+				}
+			} else {
+				if result != item.result {
+					t.Errorf("Dialect '%v' with input '%v' : FAILED, expected '%v' but got '%v'", name, item.input, item.result, result)
+				} else {
+					t.Logf("Dialect '%v' with input '%v' : PASSED, expected '%v' and got '%v'", name, item.input, item.result, result)
+				}
+			}
+		}
+	}
+}
+
+// TestDialectPlaceholders tests the rendering of named parameters in the
+// built-in dialects.
+func TestDialectPlaceholders(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for name, result := range map[string]string{
+		"generic":   "ident1 = :P1 AND ident2 > :P2",
+		"postgres":  "ident1 = $1 AND ident2 > $2",
+		"mysql":     "ident1 = ? AND ident2 > ?",
+		"sqlite":    "ident1 = ? AND ident2 > ?",
+		"sqlserver": "ident1 = @P1 AND ident2 > @P2",
+		"oracle":    "ident1 = :P1 AND ident2 > :P2",
+	} {
+		dialect, _ := GetDialect(name)
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+		codeGenerator.RenderingOptions.EnableNamedParams()
+
+		r := strings.NewReader("ident1 eq 'text' and ident2 gt 10")
+		w := new(bytes.Buffer)
+
+		if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+			t.Errorf("Dialect '%v' : FAILED, %v", name, err)
+		} else if w.String() != result {
+			t.Errorf("Dialect '%v' : FAILED, expected '%v' but got '%v'", name, result, w.String())
+		}
+	}
+}
+
+// TestDialectRegexpErrors tests that regular expressions that a dialect does
+// not support are rejected with an error that names the dialect.
+func TestDialectRegexpErrors(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for _, item := range []struct {
+		dialect string
+		input   string
+		err     string
+	}{
+		{"generic", "ident matches '^a'", "generic does not support regular expressions"},
+		{"sqlserver", "ident matches '^a'", "sqlserver does not support regular expressions"},
+		{"postgres", "ident matches '(?i)text'", "postgres does not support case-insensitive flags in regular expressions"},
+		{"postgres", "ident matches '(?i)[a-z]'", "postgres does not support case-insensitive flags in regular expressions"},
+		{"postgres", "ident matches '(?i)k'", "postgres does not support case-insensitive flags in regular expressions"},
+	} {
+		dialect, _ := GetDialect(item.dialect)
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+
+		if err := interpreter.Accept(codeGenerator, r, w); err == nil {
+			t.Errorf("Dialect '%v' with input '%v' : FAILED, expected an error but got '%v'", item.dialect, item.input, w.String())
+		} else if !strings.Contains(err.Error(), item.err) {
+			t.Errorf("Dialect '%v' with input '%v' : FAILED, expected '%v' but got '%v'", item.dialect, item.input, item.err, err)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Duration is an ISO-8601 duration broken down into its components. Negative
+// durations have all their components negative, and fractional seconds are
+// expressed in microseconds.
+type Duration struct {
+	Years        int
+	Months       int
+	Weeks        int
+	Days         int
+	Hours        int
+	Minutes      int
+	Seconds      int
+	Microseconds int
+}
+
+// isoDuration matches ISO-8601 durations, e.g. P1Y2M3DT4H5M6.5S, optionally
+// preceded by a sign. Only seconds can be fractional.
+var isoDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)(?:[.,](\d{1,6}))?S)?)?$`)
+
+// parseDuration parses the ISO-8601 duration s.
+func parseDuration(s string) (*Duration, error) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || strings.HasSuffix(s, "P") || strings.HasSuffix(s, "T") {
+		return nil, errors.Errorf("invalid iso8601 interval %s", s)
+	}
+
+	v := make([]int, 8)
+	for i, g := range m[2:] {
+		if i == len(v)-1 && len(g) > 0 {
+			g += strings.Repeat("0", 6-len(g))
+		}
+		if len(g) > 0 {
+			n, err := strconv.Atoi(g)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid iso8601 interval %s", s)
+			}
+			v[i] = n
+		}
+	}
+
+	if m[1] == "-" {
+		for i := range v {
+			v[i] = -v[i]
+		}
+	}
+
+	return &Duration{
+		Years:        v[0],
+		Months:       v[1],
+		Weeks:        v[2],
+		Days:         v[3],
+		Hours:        v[4],
+		Minutes:      v[5],
+		Seconds:      v[6],
+		Microseconds: v[7],
+	}, nil
+}
+
+// toDuration returns the duration resulting from the sum of the ISO-8601
+// intervals passed as arguments to m.
+func toDuration(m *Macro) (*Duration, error) {
+	if m.Args == nil {
+		return nil, errors.Errorf("%s: missing parameter: iso8601 interval", m.Name)
+	}
+
+	d := &Duration{}
+
+	for _, a := range m.Args {
+		if a.String == nil {
+			return nil, errors.Errorf("iso8601 interval cannot be of type %s", toTypeName(termTypeOf(a)))
+		}
+		ad, err := parseDuration(*a.String)
+		if err != nil {
+			return nil, err
+		}
+		d.add(ad)
+	}
+
+	return d, nil
+}
+
+// add adds the components of o to the components of d.
+func (d *Duration) add(o *Duration) {
+	d.Years += o.Years
+	d.Months += o.Months
+	d.Weeks += o.Weeks
+	d.Days += o.Days
+	d.Hours += o.Hours
+	d.Minutes += o.Minutes
+	d.Seconds += o.Seconds
+	d.Microseconds += o.Microseconds
+}
+
+// milliseconds returns d as a number of milliseconds, or an error if d has
+// years or months, whose length varies.
+func (d *Duration) milliseconds() (int64, error) {
+	if d.Years != 0 || d.Months != 0 {
+		return 0, errors.New("years and months cannot be converted into milliseconds")
+	}
+
+	s := int64(((d.Weeks*7+d.Days)*24+d.Hours)*60+d.Minutes)*60 + int64(d.Seconds)
+	return s*1000 + int64(d.Microseconds/1000), nil
+}
+
+// durationPart is a single component of a Duration.
+type durationPart struct {
+	value  int
+	unit   string
+	micros int // fractional seconds of SECOND components, in microseconds
+}
+
+// amount returns the value of p as a decimal number, including fractional
+// seconds, e.g. 1.5.
+func (p durationPart) amount() string {
+	if p.micros == 0 {
+		return strconv.Itoa(p.value)
+	}
+
+	return strconv.FormatFloat(float64(p.value)+float64(p.micros)/1e6, 'f', -1, 64)
+}
+
+// abs returns p with a positive value, and whether or not p was negative.
+func (p durationPart) abs() (durationPart, bool) {
+	if int64(p.value)*1e6+int64(p.micros) < 0 {
+		return durationPart{-p.value, p.unit, -p.micros}, true
+	}
+
+	return p, false
+}
+
+// parts returns the non-zero components of d, from the largest to the
+// smallest, or a single component of 0 seconds if d is zero. Units are
+// expressed in upper case and singular form, e.g. HOUR. If fractional is true,
+// then microseconds are expressed as fractional seconds, otherwise they are a
+// MICROSECOND component of their own.
+func (d *Duration) parts(fractional bool) []durationPart {
+	var parts []durationPart
+
+	for _, p := range []durationPart{
+		{d.Years, "YEAR", 0},
+		{d.Months, "MONTH", 0},
+		{d.Weeks, "WEEK", 0},
+		{d.Days, "DAY", 0},
+		{d.Hours, "HOUR", 0},
+		{d.Minutes, "MINUTE", 0},
+		{d.Seconds, "SECOND", 0},
+	} {
+		if p.unit == "SECOND" && fractional {
+			p.micros = d.Microseconds
+		}
+		if p.value != 0 || p.micros != 0 {
+			parts = append(parts, p)
+		}
+	}
+
+	if d.Microseconds != 0 && !fractional {
+		parts = append(parts, durationPart{d.Microseconds, "MICROSECOND", 0})
+	}
+
+	if len(parts) == 0 {
+		parts = append(parts, durationPart{0, "SECOND", 0})
+	}
+
+	return parts
+}
@@ -0,0 +1,726 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// wildcardEscaper escapes wildcard query metacharacters with \.
+	wildcardEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+)
+
+// ElasticsearchCodeGenerator is the CodeGenerator implementation that produces
+// Elasticsearch Query DSL from Espresso++ expressions. The resulting JSON is a
+// query clause, i.e. the value of the query element of a search request.
+type ElasticsearchCodeGenerator struct {
+	// RenderingOptions is used to control the way queries are produced.
+	RenderingOptions *RenderingOptions
+}
+
+// NewElasticsearchCodeGenerator creates a new instance of ElasticsearchCodeGenerator.
+func NewElasticsearchCodeGenerator() *ElasticsearchCodeGenerator {
+	return &ElasticsearchCodeGenerator{
+		RenderingOptions: NewRenderingOptions(),
+	}
+}
+
+// Visit lets cg access the functionality provided by i to parse the Espresso++
+// expressions in r and get back the grammar, which is then used to produce an
+// Elasticsearch query into w.
+func (cg *ElasticsearchCodeGenerator) Visit(i Interpreter, r io.Reader, w io.Writer) error {
+	if i == nil {
+		return errors.New("interpreter not specified")
+	}
+
+	grammar, err := i.Parse(r)
+	if err != nil {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r)
+		return errors.Wrapf(err, "error parsing %v", buf.String())
+	}
+
+	rr, err := cg.Generate(grammar)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, rr.Code)
+	return err
+}
+
+// Generate produces an Elasticsearch query from g. It is safe for concurrent use.
+// If cg has no RenderingOptions, then the default ones are used.
+func (cg *ElasticsearchCodeGenerator) Generate(g *Grammar) (*RenderResult, error) {
+	c := *cg
+	if c.RenderingOptions == nil {
+		c.RenderingOptions = NewRenderingOptions()
+	}
+
+	if err := c.RenderingOptions.validateOperators(g); err != nil {
+		return nil, errors.Wrapf(err, "error generating elasticsearch")
+	}
+
+	s, err := c.emitGrammar(g)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error generating elasticsearch")
+	}
+
+	return &RenderResult{Code: s}, nil
+}
+
+// emitGrammar renders g.
+func (cg *ElasticsearchCodeGenerator) emitGrammar(g *Grammar) (string, error) {
+	return cg.emitDisjunction(g.Disjunction)
+}
+
+// emitDisjunction renders d, where conjunctions are combined with bool should.
+func (cg *ElasticsearchCodeGenerator) emitDisjunction(d *Disjunction) (string, error) {
+	items := make([]string, len(d.Conjunctions))
+
+	for i, c := range d.Conjunctions {
+		s, err := cg.emitConjunction(c)
+		if err != nil {
+			return "", err
+		}
+		items[i] = s
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+
+	return fmt.Sprintf(`{"bool":{"should":[%s],"minimum_should_match":1}}`, strings.Join(items, ",")), nil
+}
+
+// emitConjunction renders c, where predicates are combined with bool filter.
+func (cg *ElasticsearchCodeGenerator) emitConjunction(c *Conjunction) (string, error) {
+	predicates := make([]string, len(c.Expressions))
+
+	for i, e := range c.Expressions {
+		s, err := cg.emitExpression(e)
+		if err != nil {
+			return "", err
+		}
+		predicates[i] = s
+	}
+
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+
+	return fmt.Sprintf(`{"bool":{"filter":[%s]}}`, strings.Join(predicates, ",")), nil
+}
+
+// emitExpression renders e.
+func (cg *ElasticsearchCodeGenerator) emitExpression(e *Expression) (string, error) {
+	var err error
+	var s string
+
+	if e.SubExpression != nil {
+		s, err = cg.emitSubExpression(e.SubExpression)
+	} else if e.Comparison != nil {
+		s, err = cg.emitComparison(e.Comparison)
+	} else if e.Equality != nil {
+		s, err = cg.emitEquality(e.Equality)
+	} else if e.Range != nil {
+		s, err = cg.emitRange(e.Range)
+	} else if e.Match != nil {
+		s, err = cg.emitMatch(e.Match)
+	} else if e.In != nil {
+		s, err = cg.emitIn(e.In)
+	} else if e.Has != nil {
+		s, err = cg.emitHas(e.Has)
+	} else if e.Quantified != nil {
+		s, err = cg.emitQuantified(e.Quantified)
+	} else if e.Is != nil {
+		s, err = cg.emitIs(e.Is)
+	}
+
+	return s, err
+}
+
+// emitSubExpression renders se.
+func (cg *ElasticsearchCodeGenerator) emitSubExpression(se *SubExpression) (string, error) {
+	s, err := cg.emitDisjunction(se.Disjunction)
+	if err != nil {
+		return "", err
+	}
+
+	if se.Not {
+		s = mustNot(s)
+	}
+
+	return s, nil
+}
+
+// emitComparison renders c.
+func (cg *ElasticsearchCodeGenerator) emitComparison(c *Comparison) (string, error) {
+	f, op, v, tt, err := cg.emitOperands(c.TermOrMath1, c.Op, c.TermOrMath2)
+	if err != nil {
+		return "", err
+	} else if !isOrdered(tt) {
+		return "", errors.Errorf("cannot compare values of type %s", toTypeName(tt))
+	}
+
+	return fmt.Sprintf(`{"range":{%s:{"%s":%s}}}`, f, op, v), nil
+}
+
+// emitEquality renders e, where date math is tested with a range query since
+// Elasticsearch does not evaluate it in term queries.
+func (cg *ElasticsearchCodeGenerator) emitEquality(e *Equality) (string, error) {
+	f, op, v, _, err := cg.emitOperands(e.TermOrMath1, e.Op, e.TermOrMath2)
+	if err != nil {
+		return "", err
+	}
+
+	tm := e.TermOrMath2
+	if !isField(e.TermOrMath1) {
+		tm = e.TermOrMath1
+	}
+
+	s := fmt.Sprintf(`{"term":{%s:%s}}`, f, v)
+	if t := termOf(tm); t == nil || cg.isDateMath(t) {
+		s = fmt.Sprintf(`{"range":{%s:{"gte":%s,"lte":%s}}}`, f, v, v)
+	}
+	if op == "neq" {
+		s = mustNot(s)
+	}
+
+	return s, nil
+}
+
+// emitOperands renders the operands of the binary operation tm1 op tm2, one of
+// which must be a field. If the field is the right operand, then operands are
+// swapped and the returned operator is mirrored accordingly.
+func (cg *ElasticsearchCodeGenerator) emitOperands(tm1 *TermOrMath, op string, tm2 *TermOrMath) (string, string, string, termType, error) {
+	if !isField(tm1) {
+		if !isField(tm2) {
+			return "", "", "", undefType, errors.Errorf("%s requires a field as one of its operands", op)
+		}
+		tm1, tm2 = tm2, tm1
+		op = mirrorOp(op)
+	}
+
+	f, err := cg.emitField(*termOf(tm1).Identifier)
+	if err != nil {
+		return "", "", "", undefType, err
+	}
+
+	var v string
+	var tt termType
+	if t := termOf(tm2); t != nil {
+		v, tt, err = cg.emitFieldValue(*termOf(tm1).Identifier, t)
+	} else {
+		v, tt, err = cg.emitDateMath(tm2)
+	}
+	if err != nil {
+		return "", "", "", undefType, err
+	}
+
+	if tt, err = validateTypes(cg.RenderingOptions.fieldType(*termOf(tm1).Identifier), tt); err != nil {
+		return "", "", "", undefType, err
+	}
+
+	return f, op, v, tt, nil
+}
+
+// emitRange renders r.
+func (cg *ElasticsearchCodeGenerator) emitRange(r *Range) (string, error) {
+	if !isField(r.TermOrMath1) {
+		return "", errors.Errorf("%s requires a field as its first operand", r.Between)
+	}
+
+	f, err := cg.emitField(*termOf(r.TermOrMath1).Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	v1, tt1, err := cg.emitValue(r.TermOrMath2)
+	if err != nil {
+		return "", err
+	}
+
+	v2, tt2, err := cg.emitValue(r.TermOrMath3)
+	if err != nil {
+		return "", err
+	}
+
+	tt, err := validateTypes(tt1, tt2)
+	if err != nil {
+		return "", err
+	}
+	if tt, err = validateTypes(cg.RenderingOptions.fieldType(*termOf(r.TermOrMath1).Identifier), tt); err != nil {
+		return "", err
+	} else if !isOrdered(tt) {
+		return "", errors.Errorf("cannot range values of type %s", toTypeName(tt))
+	}
+
+	return fmt.Sprintf(`{"range":{%s:{"gte":%s,"lte":%s}}}`, f, v1, v2), nil
+}
+
+// emitMatch renders m.
+func (cg *ElasticsearchCodeGenerator) emitMatch(m *Match) (string, error) {
+	if m.Term1.Identifier == nil || m.Term2.String == nil {
+		tt, err := validateTypes(termTypeOf(m.Term1), termTypeOf(m.Term2))
+		if err != nil {
+			return "", err
+		} else if tt != stringType {
+			return "", errors.Errorf("cannot match values of type %s", toTypeName(tt))
+		}
+		return "", errors.Errorf("%s requires a field and a string", m.Op)
+	}
+
+	f, err := cg.emitField(*m.Term1.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if tt, err := validateTypes(cg.RenderingOptions.fieldType(*m.Term1.Identifier), stringType); err != nil {
+		return "", err
+	} else if tt != stringType {
+		return "", errors.Errorf("cannot match values of type %s", toTypeName(tt))
+	}
+
+	op, ci := matchOp(m.Op)
+	q, p := "wildcard", *m.Term2.String
+
+	switch op {
+	case "matches":
+		return "", errors.New("elasticsearch does not support matches since regexp queries are always anchored")
+	case "startswith":
+		q = "prefix"
+	case "eq":
+		q = "term"
+	case "endswith":
+		p = "*" + wildcardEscaper.Replace(p)
+	case "contains":
+		p = "*" + wildcardEscaper.Replace(p) + "*"
+	}
+
+	if ci {
+		return fmt.Sprintf(`{%q:{%s:{"value":%s,"case_insensitive":true}}}`, q, f, jsonString(p)), nil
+	}
+
+	return fmt.Sprintf(`{%q:{%s:%s}}`, q, f, jsonString(p)), nil
+}
+
+// emitIn renders i as a terms query.
+func (cg *ElasticsearchCodeGenerator) emitIn(i *In) (string, error) {
+	if !isField(i.TermOrMath) {
+		return "", errors.New("in requires a field as its first operand")
+	}
+
+	f, err := cg.emitField(*termOf(i.TermOrMath).Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	tt := cg.RenderingOptions.fieldType(*termOf(i.TermOrMath).Identifier)
+	terms := make([]string, len(i.Terms))
+
+	for j, t := range i.Terms {
+		if cg.isDateMath(t) {
+			return "", errors.Errorf("elasticsearch does not evaluate %s in terms queries", t.Macro.Name)
+		}
+
+		v, vt, err := cg.emitFieldValue(*termOf(i.TermOrMath).Identifier, t)
+		if err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, vt); err != nil {
+			return "", err
+		}
+		terms[j] = v
+	}
+
+	s := fmt.Sprintf(`{"terms":{%s:[%s]}}`, f, strings.Join(terms, ","))
+	if i.Not {
+		s = mustNot(s)
+	}
+
+	return s, nil
+}
+
+// emitHas renders h as a term query, which matches any element of an array.
+func (cg *ElasticsearchCodeGenerator) emitHas(h *Has) (string, error) {
+	f, err := cg.emitField(h.Field)
+	if err != nil {
+		return "", err
+	}
+
+	tt, err := cg.RenderingOptions.elementType(h.Field)
+	if err != nil {
+		return "", err
+	}
+
+	v, vt, err := cg.emitFieldValue(h.Field, h.Term)
+	if err != nil {
+		return "", err
+	}
+	if _, err = validateTypes(tt, vt); err != nil {
+		return "", err
+	} else if cg.isDateMath(h.Term) {
+		return fmt.Sprintf(`{"range":{%s:{"gte":%s,"lte":%s}}}`, f, v, v), nil
+	}
+
+	return fmt.Sprintf(`{"term":{%s:%s}}`, f, v), nil
+}
+
+// emitQuantified renders q as either a term, range, or terms query, which match
+// any element of an array. Tests on every element are not supported since
+// arrays are indexed as sets of values.
+func (cg *ElasticsearchCodeGenerator) emitQuantified(q *Quantified) (string, error) {
+	if q.Quantifier == "all" {
+		return "", errors.New("elasticsearch does not support all since array elements are indexed as sets of values")
+	} else if q.Op == "neq" {
+		return "", errors.New("elasticsearch does not support any neq since array elements are indexed as sets of values")
+	}
+
+	f, err := cg.emitField(q.Field)
+	if err != nil {
+		return "", err
+	}
+
+	tt, err := cg.RenderingOptions.elementType(q.Field)
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]string, len(q.terms()))
+
+	for i, t := range q.terms() {
+		if q.op() == "in" && cg.isDateMath(t) {
+			return "", errors.Errorf("elasticsearch does not evaluate %s in terms queries", t.Macro.Name)
+		}
+
+		v, vt, err := cg.emitFieldValue(q.Field, t)
+		if err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, vt); err != nil {
+			return "", err
+		}
+		values[i] = v
+	}
+
+	switch q.op() {
+	case "in":
+		return fmt.Sprintf(`{"terms":{%s:[%s]}}`, f, strings.Join(values, ",")), nil
+	case "eq":
+		if cg.isDateMath(q.terms()[0]) {
+			return fmt.Sprintf(`{"range":{%s:{"gte":%s,"lte":%s}}}`, f, values[0], values[0]), nil
+		}
+		return fmt.Sprintf(`{"term":{%s:%s}}`, f, values[0]), nil
+	}
+
+	if !isOrdered(tt) {
+		return "", errors.Errorf("cannot compare values of type %s", toTypeName(tt))
+	}
+
+	return fmt.Sprintf(`{"range":{%s:{"%s":%s}}}`, f, q.Op, values[0]), nil
+}
+
+// emitIs renders i.
+func (cg *ElasticsearchCodeGenerator) emitIs(i *Is) (string, error) {
+	var s string
+
+	if i.IsWithExplicitValue != nil {
+		f, err := cg.emitField(i.IsWithExplicitValue.Ident)
+		if err != nil {
+			return "", err
+		}
+		not := i.IsWithExplicitValue.Not
+		if i.IsWithExplicitValue.Value == "null" {
+			s = fmt.Sprintf(`{"exists":{"field":%s}}`, f)
+			not = !not
+		} else {
+			if err := cg.RenderingOptions.validateBoolField(i.IsWithExplicitValue.Ident); err != nil {
+				return "", err
+			}
+			s = fmt.Sprintf(`{"term":{%s:%s}}`, f, i.IsWithExplicitValue.Value)
+		}
+		if not {
+			s = mustNot(s)
+		}
+	} else if i.IsWithImplicitValue != nil {
+		f, err := cg.emitField(i.IsWithImplicitValue.Ident)
+		if err != nil {
+			return "", err
+		}
+		if err := cg.RenderingOptions.validateBoolField(i.IsWithImplicitValue.Ident); err != nil {
+			return "", err
+		}
+		s = fmt.Sprintf(`{"term":{%s:%t}}`, f, !i.IsWithImplicitValue.Not)
+	}
+
+	return s, nil
+}
+
+// emitField renders field f.
+func (cg *ElasticsearchCodeGenerator) emitField(f string) (string, error) {
+	n, err := cg.RenderingOptions.nativeFieldName(f)
+	if err != nil {
+		return "", err
+	}
+
+	return jsonString(n), nil
+}
+
+// emitValue renders tm as a value. Arithmetic is only supported in the form
+// of date math, i.e. durations added to or subtracted from a date.
+func (cg *ElasticsearchCodeGenerator) emitValue(tm *TermOrMath) (string, termType, error) {
+	if t := termOf(tm); t != nil {
+		return cg.emitTerm(t)
+	}
+
+	return cg.emitDateMath(tm)
+}
+
+// isDateMath returns a Boolean value indicating whether or not t is rendered as
+// date math, i.e. it is #now and the rendering options have no clock.
+func (cg *ElasticsearchCodeGenerator) isDateMath(t *Term) bool {
+	return t.Macro != nil && t.Macro.Name == "#now" && cg.RenderingOptions.GetClock() == nil
+}
+
+// emitFieldValue renders the term t compared with field f, where strings
+// compared with enumerations are rendered as their native values.
+func (cg *ElasticsearchCodeGenerator) emitFieldValue(f string, t *Term) (string, termType, error) {
+	if t.String == nil || !cg.RenderingOptions.isEnumerated(f) {
+		return cg.emitTerm(t)
+	}
+
+	nv, tt, err := cg.RenderingOptions.nativeFieldValue(f, *t.String)
+	if err != nil {
+		return "", undefType, err
+	} else if tt == stringType {
+		nv = jsonString(nv)
+	}
+
+	return nv, enumType, nil
+}
+
+// emitDateMath renders tm as an Elasticsearch date math expression.
+func (cg *ElasticsearchCodeGenerator) emitDateMath(tm *TermOrMath) (string, termType, error) {
+	if f := tm.Product.Factor; len(tm.Products) == 0 && len(tm.Product.Factors) == 0 && !f.Minus && f.SubMath != nil {
+		return cg.emitDateMath(f.SubMath)
+	}
+
+	var anchor string
+	var tt termType
+
+	t := factorTermOf(tm.Product)
+	if t != nil && t.Macro != nil && expandedClientSide(t.Macro.Name, cg.RenderingOptions) {
+		var err error
+		if t, _, err = expandCalendarMacro(t.Macro, cg.RenderingOptions.now()); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	if t == nil {
+		return "", undefType, errors.New("arithmetic is only supported as date math")
+	} else if t.Macro != nil && t.Macro.Name == "#now" {
+		if err := expectNoArgs(t.Macro); err != nil {
+			return "", undefType, err
+		}
+		anchor = "now"
+		tt = dateTimeType
+	} else if t.Date != nil {
+		if _, err := toValue(*t.Date, dateType); err != nil {
+			return "", undefType, err
+		}
+		anchor = *t.Date + "||"
+		tt = dateType
+	} else if t.DateTime != nil {
+		s, err := toElasticsearchDateTime(*t.DateTime, cg.RenderingOptions.GetLocation())
+		if err != nil {
+			return "", undefType, err
+		}
+		anchor = s + "||"
+		tt = dateTimeType
+	} else {
+		return "", undefType, errors.New("date math requires a date or #now as its first operand")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(anchor)
+
+	for _, as := range tm.Products {
+		m := durationOf(as.Product)
+		if m == nil {
+			return "", undefType, errors.New("arithmetic is only supported as date math")
+		}
+
+		d, err := cg.emitDurationMacro(m, as.Op == "sub")
+		if err != nil {
+			return "", undefType, err
+		}
+		sb.WriteString(d)
+	}
+
+	return jsonString(sb.String()), tt, nil
+}
+
+// emitTerm renders t.
+func (cg *ElasticsearchCodeGenerator) emitTerm(t *Term) (string, termType, error) {
+	var err error
+	var s string
+	var tt termType
+
+	if t.Identifier != nil {
+		err = errors.Errorf("field %s cannot be used as a value", *t.Identifier)
+	} else if t.Function != nil {
+		err = errors.Errorf("elasticsearch does not support function %s", t.Function.Name)
+	} else if t.Integer != nil {
+		tt = intType
+		s = strconv.Itoa(*t.Integer)
+	} else if t.Decimal != nil {
+		tt = decimalType
+		s = strconv.FormatFloat(*t.Decimal, 'f', -1, 64)
+	} else if t.String != nil {
+		tt = stringType
+		s = jsonString(*t.String)
+	} else if t.Date != nil {
+		tt = dateType
+		if _, err = toValue(*t.Date, tt); err == nil {
+			s = jsonString(*t.Date)
+		}
+	} else if t.Time != nil {
+		tt = timeType
+		if _, err = toValue(*t.Time, tt); err == nil {
+			s = jsonString(*t.Time)
+		}
+	} else if t.DateTime != nil {
+		tt = dateTimeType
+		if s, err = toElasticsearchDateTime(*t.DateTime, cg.RenderingOptions.GetLocation()); err == nil {
+			s = jsonString(s)
+		}
+	} else if t.Bool != nil {
+		tt = boolType
+		s = *t.Bool
+	} else if t.Macro != nil {
+		s, tt, err = cg.emitMacro(t.Macro)
+	}
+
+	return s, tt, err
+}
+
+// emitMacro renders m.
+func (cg *ElasticsearchCodeGenerator) emitMacro(m *Macro) (string, termType, error) {
+	var err error
+	var s string
+	var t termType
+
+	switch m.Name {
+	case "#now":
+		if err = expectNoArgs(m); err == nil {
+			if cg.RenderingOptions.GetClock() != nil {
+				s, t, err = cg.emitCalendarMacro(m)
+			} else {
+				s, t = jsonString("now"), dateTimeType
+			}
+		}
+	case "#duration":
+		err = errors.Errorf("%s can only be added to or subtracted from a date", m.Name)
+	case "#today", "#startof", "#endof", "#ago":
+		s, t, err = cg.emitCalendarMacro(m)
+	default:
+		s, t, err = cg.emitUserMacro(m)
+	}
+
+	return s, t, err
+}
+
+// emitCalendarMacro renders m as the date it expands to relative to the clock
+// and time zone of the rendering options.
+func (cg *ElasticsearchCodeGenerator) emitCalendarMacro(m *Macro) (string, termType, error) {
+	t, tt, err := expandCalendarMacro(m, cg.RenderingOptions.now())
+	if err != nil {
+		return "", undefType, err
+	}
+
+	s, _, err := cg.emitTerm(t)
+	return s, tt, err
+}
+
+// emitUserMacro renders the user-defined macro m with the renderer for
+// elasticsearch.
+func (cg *ElasticsearchCodeGenerator) emitUserMacro(m *Macro) (string, termType, error) {
+	e, t, err := cg.RenderingOptions.GetMacroRegistry().expand(m, "elasticsearch")
+	if err != nil {
+		return "", undefType, err
+	} else if nc, ok := e.(NativeCode); ok {
+		return string(nc), t, nil
+	}
+
+	s, _, err := cg.emitTerm(e.(*Term))
+	return s, t, err
+}
+
+// emitDurationMacro renders m as a sequence of date math units to be added, or
+// subtracted if sub is true. Date math does not support fractional seconds.
+func (cg *ElasticsearchCodeGenerator) emitDurationMacro(m *Macro, sub bool) (string, error) {
+	d, err := toDuration(m)
+	if err != nil {
+		return "", err
+	} else if d.Microseconds != 0 {
+		return "", errors.New("elasticsearch does not support fractional seconds in date math")
+	}
+
+	units := map[string]string{
+		"YEAR":   "y",
+		"MONTH":  "M",
+		"WEEK":   "w",
+		"DAY":    "d",
+		"HOUR":   "h",
+		"MINUTE": "m",
+		"SECOND": "s",
+	}
+
+	var sb strings.Builder
+
+	for _, p := range d.parts(false) {
+		p, neg := p.abs()
+		sign := "+"
+		if sub != neg {
+			sign = "-"
+		}
+		sb.WriteString(fmt.Sprintf("%s%d%s", sign, p.value, units[p.unit]))
+	}
+
+	return sb.String(), nil
+}
+
+// mustNot negates the query clause s.
+func mustNot(s string) string {
+	return fmt.Sprintf(`{"bool":{"must_not":[%s]}}`, s)
+}
+
+// toElasticsearchDateTime normalizes the RFC 3339 timestamp s so that it
+// complies with the default Elasticsearch date format, i.e. with offsets in the
+// form Z or +hh:mm. Timestamps without offset are interpreted in loc and
+// converted to UTC.
+func toElasticsearchDateTime(s string, loc *time.Location) (string, error) {
+	if !hasOffset(s) {
+		return toUTCDateTime(s, loc)
+	}
+
+	v, err := toValue(s, dateTimeType)
+	if err != nil {
+		return "", err
+	}
+
+	return v.(time.Time).Format("2006-01-02T15:04:05.999999999Z07:00"), nil
+}
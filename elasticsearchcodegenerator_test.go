@@ -0,0 +1,145 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestGenerateElasticsearch tests the generation of Elasticsearch queries from
+// Espresso++ expressions.
+func TestGenerateElasticsearch(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewElasticsearchCodeGenerator()
+	codeGenerator.RenderingOptions.AddFieldProps("mapped", &FieldProps{Filterable: true, NativeName: "native"})
+	codeGenerator.RenderingOptions.AddFieldProps("hidden", &FieldProps{Filterable: false})
+	codeGenerator.RenderingOptions.AddFieldProps("restricted", &FieldProps{Filterable: true, Operators: []string{"eq", "in"}})
+	codeGenerator.RenderingOptions.AddFieldProps("computed", &FieldProps{Filterable: true, NativeExpr: "first || ' ' || last"})
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+	for _, item := range getElasticsearchTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateElasticsearchWithTypedFields tests the generation of Elasticsearch queries from
+// Espresso++ expressions that reference fields with a declared type or a set of
+// allowed values.
+func TestGenerateElasticsearchWithTypedFields(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewElasticsearchCodeGenerator()
+	codeGenerator.RenderingOptions.Fields(getTypedFields())
+
+	for _, item := range getElasticsearchTypedFieldsTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateElasticsearchWithClock tests the generation of Elasticsearch
+// queries from Espresso++ expressions whose macros and literals are resolved
+// relative to a clock and time zone.
+func TestGenerateElasticsearchWithClock(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewElasticsearchCodeGenerator()
+	codeGenerator.RenderingOptions.SetClock(getClock())
+	codeGenerator.RenderingOptions.SetLocation(getLocation())
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+	for _, item := range getClockTestDataItems()["elasticsearch"] {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateElasticsearchWithoutRenderingOptions tests the generation of
+// Elasticsearch queries with a code generator that has no rendering options.
+func TestGenerateElasticsearchWithoutRenderingOptions(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := &ElasticsearchCodeGenerator{}
+
+	for _, item := range []testDataItem{
+		{"ident eq 'text' and ident2 gt 10", `{"bool":{"filter":[{"term":{"ident":"text"}},{"range":{"ident2":{"gt":10}}}]}}`, false},
+		{"ident eq '2020-03-15T14:10:25'", `{"term":{"ident":"2020-03-15T14:10:25"}}`, false},
+		{"ident lt #now", `{"range":{"ident":{"lt":"now"}}}`, false},
+		{"ident eq #undefined", "", true},
+	} {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// function is the signature of a builtin scalar function.
+type function struct {
+	// params is the types of the mandatory parameters, where identType denotes
+	// a parameter of any type. All the parameters of any type must be passed
+	// arguments of the same type.
+	params []termType
+
+	// optional is the types of the optional parameters.
+	optional []termType
+
+	// variadic specifies whether or not the last parameter can be repeated.
+	variadic bool
+
+	// timeOfDay specifies whether or not the parameters of type datetime also
+	// accept time-of-day values.
+	timeOfDay bool
+
+	// result is the type of the result, where identType denotes the type of
+	// the first argument.
+	result termType
+}
+
+// functions maps the names of the builtin scalar functions to their signature.
+var functions = map[string]*function{
+	"lower":     {params: []termType{stringType}, result: stringType},
+	"upper":     {params: []termType{stringType}, result: stringType},
+	"trim":      {params: []termType{stringType}, result: stringType},
+	"length":    {params: []termType{stringType}, result: intType},
+	"abs":       {params: []termType{decimalType}, result: identType},
+	"round":     {params: []termType{decimalType}, optional: []termType{intType}, result: identType},
+	"floor":     {params: []termType{decimalType}, result: identType},
+	"ceil":      {params: []termType{decimalType}, result: identType},
+	"coalesce":  {params: []termType{identType, identType}, variadic: true, result: identType},
+	"year":      {params: []termType{dateTimeType}, result: intType},
+	"month":     {params: []termType{dateTimeType}, result: intType},
+	"day":       {params: []termType{dateTimeType}, result: intType},
+	"hour":      {params: []termType{dateTimeType}, timeOfDay: true, result: intType},
+	"dayofweek": {params: []termType{dateTimeType}, result: intType},
+}
+
+// validateCall verifies whether or not the builtin function with the specified
+// name can be called with arguments of the specified types, and if it can, it
+// returns the type of the result.
+func validateCall(name string, args []termType) (termType, error) {
+	f, ok := functions[name]
+	if !ok {
+		return undefType, errors.Errorf("function %s not supported", name)
+	}
+
+	min, max := len(f.params), len(f.params)+len(f.optional)
+	if f.variadic && len(args) < min {
+		return undefType, errors.Errorf("function %s expects at least %d arguments, got %d", name, min, len(args))
+	} else if !f.variadic && min == max && len(args) != min {
+		return undefType, errors.Errorf("function %s expects %d arguments, got %d", name, min, len(args))
+	} else if !f.variadic && (len(args) < min || len(args) > max) {
+		return undefType, errors.Errorf("function %s expects %d to %d arguments, got %d", name, min, max, len(args))
+	}
+
+	params := append(append([]termType{}, f.params...), f.optional...)
+	generic := identType
+
+	for i, t := range args {
+		p := params[len(params)-1]
+		if i < len(params) {
+			p = params[i]
+		}
+
+		var err error
+		if p == identType {
+			generic, err = validateTypes(generic, t)
+		} else if t != identType && t != p && !coercible(t, p) && !(f.timeOfDay && p == dateTimeType && t == timeType) {
+			err = errors.Errorf("type %s is not compatible with type %s", toTypeName(t), toTypeName(p))
+		}
+		if err != nil {
+			return undefType, errors.Wrapf(err, "invalid argument %d of function %s", i+1, name)
+		}
+	}
+
+	if f.result != identType {
+		return f.result, nil
+	} else if generic != identType {
+		return generic, nil
+	}
+
+	return args[0], nil
+}
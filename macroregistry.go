@@ -0,0 +1,292 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NativeCode is code in the target language that a MacroRenderer returns to be
+// rendered as is, e.g. DATE_TRUNC('year', CURRENT_DATE). NativeCode is trusted
+// and must never be derived from user input.
+type NativeCode string
+
+// MacroRenderer renders a user-defined macro called with the specified
+// arguments, i.e. the values of the literals passed to the macro as described
+// for NamedParam. It returns either NativeCode or a value of the result type of
+// the macro, which is then rendered as a literal or bound to a named parameter.
+type MacroRenderer func(args []interface{}) (interface{}, error)
+
+// MacroDef is the definition of a user-defined macro.
+type MacroDef struct {
+	// Params is the types of the parameters of the macro, whose arguments
+	// must be literals. Untyped parameters accept literals of any type.
+	Params []FieldType
+
+	// Result is the type of the value the macro expands to. If untyped, then
+	// the type is inferred from the value.
+	Result FieldType
+
+	// Renderers maps the targets the macro can be rendered for to the
+	// MacroRenderer that renders it, where targets are sql, mongo, and
+	// elasticsearch. Renderers for a given SQL dialect, e.g. postgres, take
+	// precedence over the one for sql.
+	Renderers map[string]MacroRenderer
+}
+
+// MacroRegistry is the set of user-defined macros, e.g. #current_user or
+// #tenant_id, which are available in addition to the builtin ones. It is safe
+// for concurrent use.
+type MacroRegistry struct {
+	mu     sync.RWMutex
+	macros map[string]*MacroDef
+}
+
+var (
+	// builtinMacros contains the names of the macros that are always available.
+	builtinMacros = map[string]bool{
+		"#now":      true,
+		"#duration": true,
+		"#today":    true,
+		"#startof":  true,
+		"#endof":    true,
+		"#ago":      true,
+	}
+
+	// macroName matches valid macro names.
+	macroName = regexp.MustCompile(`^#[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// NewMacroRegistry creates a new instance of MacroRegistry.
+func NewMacroRegistry() *MacroRegistry {
+	return &MacroRegistry{
+		macros: make(map[string]*MacroDef),
+	}
+}
+
+// Register adds the definition of the macro with the specified name, e.g.
+// #tenant_id, to the registry. Builtin macros cannot be redefined.
+func (mr *MacroRegistry) Register(name string, md *MacroDef) error {
+	if !macroName.MatchString(name) {
+		return errors.Errorf("invalid macro name %v", name)
+	}
+
+	if builtinMacros[name] {
+		return errors.Errorf("macro %v is builtin and cannot be redefined", name)
+	}
+
+	if md == nil || len(md.Renderers) == 0 {
+		return errors.Errorf("renderers for macro %v not specified", name)
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.macros[name] = md
+	return nil
+}
+
+// Unregister removes the definition of the macro with the specified name from
+// the registry.
+func (mr *MacroRegistry) Unregister(name string) *MacroDef {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	md := mr.macros[name]
+	if md != nil {
+		delete(mr.macros, name)
+	}
+
+	return md
+}
+
+// Get retrieves the definition of the macro with the specified name from the
+// registry.
+func (mr *MacroRegistry) Get(name string) *MacroDef {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	return mr.macros[name]
+}
+
+// Names returns the sorted names of the available macros, including the
+// builtin ones.
+func (mr *MacroRegistry) Names() []string {
+	var names []string
+
+	for n := range builtinMacros {
+		names = append(names, n)
+	}
+
+	if mr != nil {
+		mr.mu.RLock()
+		for n := range mr.macros {
+			names = append(names, n)
+		}
+		mr.mu.RUnlock()
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// expand expands the user-defined macro m with the renderer of the first of
+// targets it has one for, and returns either NativeCode or the literal term the
+// macro expands to, along with its type.
+func (mr *MacroRegistry) expand(m *Macro, targets ...string) (interface{}, termType, error) {
+	var md *MacroDef
+	if mr != nil {
+		md = mr.Get(m.Name)
+	}
+
+	if md == nil {
+		return nil, undefType, errors.Errorf("macro %s not supported, expected one of %s", m.Name, strings.Join(mr.Names(), ", "))
+	}
+
+	var r MacroRenderer
+	for _, t := range targets {
+		if r = md.Renderers[t]; r != nil {
+			break
+		}
+	}
+
+	if r == nil {
+		return nil, undefType, errors.Errorf("macro %s cannot be rendered for %s", m.Name, targets[0])
+	}
+
+	args, err := macroArgs(m, md.Params)
+	if err != nil {
+		return nil, undefType, err
+	}
+
+	v, err := r(args)
+	if err != nil {
+		return nil, undefType, errors.Wrapf(err, "error rendering macro %s", m.Name)
+	}
+
+	rt := md.Result.termType()
+	if nc, ok := v.(NativeCode); ok {
+		return nc, rt, nil
+	}
+
+	t, err := literalTerm(v, rt)
+	if err != nil {
+		return nil, undefType, errors.Wrapf(err, "invalid value of macro %s", m.Name)
+	}
+
+	tt, err := validateTypes(rt, termTypeOf(t))
+	if err != nil {
+		return nil, undefType, errors.Wrapf(err, "invalid value of macro %s", m.Name)
+	}
+
+	return t, tt, nil
+}
+
+// macroArgs verifies whether or not the arguments of m are literals of the
+// types in params, and if they are, it returns their values.
+func macroArgs(m *Macro, params []FieldType) ([]interface{}, error) {
+	if len(m.Args) != len(params) {
+		return nil, errors.Errorf("macro %s expects %d arguments, got %d", m.Name, len(params), len(m.Args))
+	}
+
+	args := make([]interface{}, len(m.Args))
+
+	for i, a := range m.Args {
+		at, pt := termTypeOf(a), params[i].termType()
+		if a.Identifier != nil || a.Function != nil || a.Macro != nil {
+			return nil, errors.Errorf("argument %d of macro %s must be a literal", i+1, m.Name)
+		} else if pt != identType && at != pt && !coercible(at, pt) {
+			return nil, errors.Errorf("invalid argument %d of macro %s: type %s is not compatible with type %s", i+1, m.Name, toTypeName(at), toTypeName(pt))
+		}
+
+		v, err := toValue(rawLiteral(a), at)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	return args, nil
+}
+
+// rawLiteral returns the raw value of the literal t.
+func rawLiteral(t *Term) string {
+	var s string
+
+	if t.Integer != nil {
+		s = strconv.Itoa(*t.Integer)
+	} else if t.Decimal != nil {
+		s = strconv.FormatFloat(*t.Decimal, 'f', -1, 64)
+	} else if t.String != nil {
+		s = *t.String
+	} else if t.Date != nil {
+		s = *t.Date
+	} else if t.Time != nil {
+		s = *t.Time
+	} else if t.DateTime != nil {
+		s = *t.DateTime
+	} else if t.Bool != nil {
+		s = *t.Bool
+	}
+
+	return s
+}
+
+// literalTerm returns the literal term that denotes v, where strings and times
+// are rendered according to t, e.g. as dates if t is dateType. Strings that
+// denote dates or times are validated against their format.
+func literalTerm(v interface{}, t termType) (*Term, error) {
+	term := &Term{}
+
+	switch x := v.(type) {
+	case int:
+		term.Integer = &x
+	case int64:
+		i := int(x)
+		term.Integer = &i
+	case float64:
+		term.Decimal = &x
+	case bool:
+		s := strconv.FormatBool(x)
+		term.Bool = &s
+	case string:
+		switch t {
+		case dateType, dateTimeType:
+			d, err := toValue(x, t)
+			if err != nil {
+				return nil, err
+			}
+			return literalTerm(d, t)
+		case timeType:
+			if _, err := time.Parse("15:04:05", x); err != nil {
+				return nil, errors.Wrapf(err, "invalid time literal %s", x)
+			}
+			term.Time = &x
+		default:
+			term.String = &x
+		}
+	case time.Time:
+		if t == dateType {
+			s := x.Format("2006-01-02")
+			term.Date = &s
+		} else {
+			s := x.Format("2006-01-02T15:04:05.999999999Z07:00")
+			term.DateTime = &s
+		}
+	default:
+		return nil, errors.Errorf("values of type %T not supported", v)
+	}
+
+	return term, nil
+}
@@ -0,0 +1,881 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// mongoFunctions maps the builtin scalar functions to their aggregation
+	// operators.
+	mongoFunctions = map[string]string{
+		"lower":     "$toLower",
+		"upper":     "$toUpper",
+		"trim":      "$trim",
+		"length":    "$strLenCP",
+		"abs":       "$abs",
+		"round":     "$round",
+		"floor":     "$floor",
+		"ceil":      "$ceil",
+		"coalesce":  "$ifNull",
+		"year":      "$year",
+		"month":     "$month",
+		"day":       "$dayOfMonth",
+		"hour":      "$hour",
+		"dayofweek": "$dayOfWeek",
+	}
+)
+
+// MongoCodeGenerator is the CodeGenerator implementation that produces MongoDB
+// filter documents from Espresso++ expressions. Filter documents are rendered
+// as MongoDB Extended JSON.
+type MongoCodeGenerator struct {
+	// RenderingOptions is used to control the way filter documents are produced.
+	RenderingOptions *RenderingOptions
+}
+
+// NewMongoCodeGenerator creates a new instance of MongoCodeGenerator.
+func NewMongoCodeGenerator() *MongoCodeGenerator {
+	return &MongoCodeGenerator{
+		RenderingOptions: NewRenderingOptions(),
+	}
+}
+
+// Visit lets cg access the functionality provided by i to parse the Espresso++
+// expressions in r and get back the grammar, which is then used to produce a
+// MongoDB filter document into w.
+func (cg *MongoCodeGenerator) Visit(i Interpreter, r io.Reader, w io.Writer) error {
+	if i == nil {
+		return errors.New("interpreter not specified")
+	}
+
+	grammar, err := i.Parse(r)
+	if err != nil {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r)
+		return errors.Wrapf(err, "error parsing %v", buf.String())
+	}
+
+	rr, err := cg.Generate(grammar)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, rr.Code)
+	return err
+}
+
+// Generate produces a MongoDB filter document from g. It is safe for concurrent use.
+// If cg has no RenderingOptions, then the default ones are used.
+func (cg *MongoCodeGenerator) Generate(g *Grammar) (*RenderResult, error) {
+	c := *cg
+	if c.RenderingOptions == nil {
+		c.RenderingOptions = NewRenderingOptions()
+	}
+
+	if err := c.RenderingOptions.validateOperators(g); err != nil {
+		return nil, errors.Wrapf(err, "error generating mongo")
+	}
+
+	s, err := c.emitGrammar(g)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error generating mongo")
+	}
+
+	return &RenderResult{Code: s}, nil
+}
+
+// emitGrammar renders g.
+func (cg *MongoCodeGenerator) emitGrammar(g *Grammar) (string, error) {
+	return cg.emitDisjunction(g.Disjunction)
+}
+
+// emitDisjunction renders d, where conjunctions are combined with $or.
+func (cg *MongoCodeGenerator) emitDisjunction(d *Disjunction) (string, error) {
+	items := make([]string, len(d.Conjunctions))
+
+	for i, c := range d.Conjunctions {
+		s, err := cg.emitConjunction(c)
+		if err != nil {
+			return "", err
+		}
+		items[i] = s
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+
+	return fmt.Sprintf(`{"$or":[%s]}`, strings.Join(items, ",")), nil
+}
+
+// emitConjunction renders c, where predicates are combined with $and.
+func (cg *MongoCodeGenerator) emitConjunction(c *Conjunction) (string, error) {
+	predicates := make([]string, len(c.Expressions))
+
+	for i, e := range c.Expressions {
+		s, err := cg.emitExpression(e)
+		if err != nil {
+			return "", err
+		}
+		predicates[i] = s
+	}
+
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+
+	return fmt.Sprintf(`{"$and":[%s]}`, strings.Join(predicates, ",")), nil
+}
+
+// emitExpression renders e.
+func (cg *MongoCodeGenerator) emitExpression(e *Expression) (string, error) {
+	var err error
+	var s string
+
+	if e.SubExpression != nil {
+		s, err = cg.emitSubExpression(e.SubExpression)
+	} else if e.Comparison != nil {
+		s, err = cg.emitComparison(e.Comparison)
+	} else if e.Equality != nil {
+		s, err = cg.emitEquality(e.Equality)
+	} else if e.Range != nil {
+		s, err = cg.emitRange(e.Range)
+	} else if e.Match != nil {
+		s, err = cg.emitMatch(e.Match)
+	} else if e.In != nil {
+		s, err = cg.emitIn(e.In)
+	} else if e.Has != nil {
+		s, err = cg.emitHas(e.Has)
+	} else if e.Quantified != nil {
+		s, err = cg.emitQuantified(e.Quantified)
+	} else if e.Is != nil {
+		s, err = cg.emitIs(e.Is)
+	}
+
+	return s, err
+}
+
+// emitSubExpression renders se. MongoDB does not support $not at the top level
+// of a filter, so negated sub-expressions are rendered with $nor.
+func (cg *MongoCodeGenerator) emitSubExpression(se *SubExpression) (string, error) {
+	s, err := cg.emitDisjunction(se.Disjunction)
+	if err != nil {
+		return "", err
+	}
+
+	if se.Not {
+		s = fmt.Sprintf(`{"$nor":[%s]}`, s)
+	}
+
+	return s, nil
+}
+
+// emitComparison renders c.
+func (cg *MongoCodeGenerator) emitComparison(c *Comparison) (string, error) {
+	s, tt, err := cg.emitOperation(c.TermOrMath1, c.Op, c.TermOrMath2)
+	if err != nil {
+		return "", err
+	} else if !isOrdered(tt) {
+		return "", errors.Errorf("cannot compare values of type %s", toTypeName(tt))
+	}
+
+	return s, nil
+}
+
+// emitEquality renders e.
+func (cg *MongoCodeGenerator) emitEquality(e *Equality) (string, error) {
+	s, _, err := cg.emitOperation(e.TermOrMath1, e.Op, e.TermOrMath2)
+	return s, err
+}
+
+// emitOperation renders the binary operation tm1 op tm2. Operations between a
+// field and a literal are rendered as query operators, whereas any other
+// operation is rendered as an aggregation expression.
+func (cg *MongoCodeGenerator) emitOperation(tm1 *TermOrMath, op string, tm2 *TermOrMath) (string, termType, error) {
+	if isField(tm1) && isLiteral(tm2) {
+		return cg.emitFieldOperation(*termOf(tm1).Identifier, op, termOf(tm2))
+	} else if isLiteral(tm1) && isField(tm2) {
+		return cg.emitFieldOperation(*termOf(tm2).Identifier, mirrorOp(op), termOf(tm1))
+	}
+
+	t1, tt1, err := cg.emitAggTermOrMath(tm1)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	t2, tt2, err := cg.emitAggTermOrMath(tm2)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	tt, err := validateTypes(tt1, tt2)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	return fmt.Sprintf(`{"$expr":{"%s":[%s,%s]}}`, toMongoOp(op), t1, t2), tt, nil
+}
+
+// emitFieldOperation renders the query operator op applied to field f and
+// literal t.
+func (cg *MongoCodeGenerator) emitFieldOperation(f string, op string, t *Term) (string, termType, error) {
+	n, err := cg.RenderingOptions.nativeFieldName(f)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	v, tt, err := cg.emitFieldValue(f, t)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	if tt, err = validateTypes(cg.RenderingOptions.fieldType(f), tt); err != nil {
+		return "", undefType, err
+	}
+
+	return fmt.Sprintf(`{%s:{"%s":%s}}`, jsonString(n), toMongoOp(op), v), tt, nil
+}
+
+// emitFieldValue renders the literal t compared with field f, where strings
+// compared with enumerations are rendered as their native values.
+func (cg *MongoCodeGenerator) emitFieldValue(f string, t *Term) (string, termType, error) {
+	if t.String == nil || !cg.RenderingOptions.isEnumerated(f) {
+		return cg.emitValue(t)
+	}
+
+	nv, tt, err := cg.RenderingOptions.nativeFieldValue(f, *t.String)
+	if err != nil {
+		return "", undefType, err
+	} else if tt == stringType {
+		nv = jsonString(nv)
+	}
+
+	return nv, enumType, nil
+}
+
+// emitRange renders r.
+func (cg *MongoCodeGenerator) emitRange(r *Range) (string, error) {
+	var s string
+	var tt termType
+
+	if isField(r.TermOrMath1) && isLiteral(r.TermOrMath2) && isLiteral(r.TermOrMath3) {
+		f := *termOf(r.TermOrMath1).Identifier
+		n, err := cg.RenderingOptions.nativeFieldName(f)
+		if err != nil {
+			return "", err
+		}
+
+		v1, tt1, err := cg.emitValue(termOf(r.TermOrMath2))
+		if err != nil {
+			return "", err
+		}
+
+		v2, tt2, err := cg.emitValue(termOf(r.TermOrMath3))
+		if err != nil {
+			return "", err
+		}
+
+		if tt, err = validateTypes(tt1, tt2); err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(cg.RenderingOptions.fieldType(f), tt); err != nil {
+			return "", err
+		}
+
+		s = fmt.Sprintf(`{%s:{"$gte":%s,"$lte":%s}}`, jsonString(n), v1, v2)
+	} else {
+		t1, tt1, err := cg.emitAggTermOrMath(r.TermOrMath1)
+		if err != nil {
+			return "", err
+		}
+
+		t2, tt2, err := cg.emitAggTermOrMath(r.TermOrMath2)
+		if err != nil {
+			return "", err
+		}
+
+		t3, tt3, err := cg.emitAggTermOrMath(r.TermOrMath3)
+		if err != nil {
+			return "", err
+		}
+
+		if tt, err = validateTypes(tt1, tt2); err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, tt3); err != nil {
+			return "", err
+		}
+
+		s = fmt.Sprintf(`{"$expr":{"$and":[{"$gte":[%s,%s]},{"$lte":[%s,%s]}]}}`, t1, t2, t1, t3)
+	}
+
+	if !isOrdered(tt) {
+		return "", errors.Errorf("cannot range values of type %s", toTypeName(tt))
+	}
+
+	return s, nil
+}
+
+// emitMatch renders m.
+func (cg *MongoCodeGenerator) emitMatch(m *Match) (string, error) {
+	if m.Term1.Identifier == nil || m.Term2.String == nil {
+		tt, err := validateTypes(termTypeOf(m.Term1), termTypeOf(m.Term2))
+		if err != nil {
+			return "", err
+		} else if tt != stringType {
+			return "", errors.Errorf("cannot match values of type %s", toTypeName(tt))
+		}
+		return "", errors.Errorf("%s requires a field and a string", m.Op)
+	}
+
+	n, err := cg.RenderingOptions.nativeFieldName(*m.Term1.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if tt, err := validateTypes(cg.RenderingOptions.fieldType(*m.Term1.Identifier), stringType); err != nil {
+		return "", err
+	} else if tt != stringType {
+		return "", errors.Errorf("cannot match values of type %s", toTypeName(tt))
+	}
+
+	p := regexp.QuoteMeta(*m.Term2.String)
+	op, ci := matchOp(m.Op)
+
+	switch op {
+	case "matches":
+		p = *m.Term2.String
+		if err := validateRegexp(p, 0, "mongo"); err != nil {
+			return "", err
+		}
+	case "startswith":
+		p = "^" + p
+	case "endswith":
+		p = p + "$"
+	case "eq":
+		p = "^" + p + "$"
+	}
+
+	if ci {
+		return fmt.Sprintf(`{%s:{"$regex":%s,"$options":"i"}}`, jsonString(n), jsonString(p)), nil
+	}
+
+	return fmt.Sprintf(`{%s:{"$regex":%s}}`, jsonString(n), jsonString(p)), nil
+}
+
+// emitIn renders i. Membership tests on fields are rendered as $in or $nin,
+// whereas any other membership test is rendered as an aggregation expression.
+func (cg *MongoCodeGenerator) emitIn(i *In) (string, error) {
+	var s string
+	var tt termType
+	var err error
+
+	emit := cg.emitAggTerm
+	if isField(i.TermOrMath) {
+		emit = func(t *Term) (string, termType, error) {
+			return cg.emitFieldValue(*termOf(i.TermOrMath).Identifier, t)
+		}
+		tt = cg.RenderingOptions.fieldType(*termOf(i.TermOrMath).Identifier)
+	} else if s, tt, err = cg.emitAggTermOrMath(i.TermOrMath); err != nil {
+		return "", err
+	}
+
+	terms := make([]string, len(i.Terms))
+
+	for j, t := range i.Terms {
+		v, vt, err := emit(t)
+		if err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, vt); err != nil {
+			return "", err
+		}
+		terms[j] = v
+	}
+
+	list := fmt.Sprintf("[%s]", strings.Join(terms, ","))
+
+	if isField(i.TermOrMath) {
+		n, err := cg.RenderingOptions.nativeFieldName(*termOf(i.TermOrMath).Identifier)
+		if err != nil {
+			return "", err
+		}
+
+		op := "$in"
+		if i.Not {
+			op = "$nin"
+		}
+
+		return fmt.Sprintf(`{%s:{"%s":%s}}`, jsonString(n), op, list), nil
+	}
+
+	s = fmt.Sprintf(`{"$in":[%s,%s]}`, s, list)
+	if i.Not {
+		s = fmt.Sprintf(`{"$not":[%s]}`, s)
+	}
+
+	return fmt.Sprintf(`{"$expr":%s}`, s), nil
+}
+
+// emitHas renders h.
+func (cg *MongoCodeGenerator) emitHas(h *Has) (string, error) {
+	return cg.emitArrayPredicate(h.Field, false, "eq", h.Term)
+}
+
+// emitQuantified renders q.
+func (cg *MongoCodeGenerator) emitQuantified(q *Quantified) (string, error) {
+	return cg.emitArrayPredicate(q.Field, q.Quantifier == "all", q.op(), q.terms()...)
+}
+
+// emitArrayPredicate renders the test of whether any element of the array field
+// f, or every element if all is true, satisfies op with the literals in ts. Tests
+// on any element are rendered as $elemMatch, whereas tests on every element are
+// rendered as the absence of elements that satisfy the negated operator.
+func (cg *MongoCodeGenerator) emitArrayPredicate(f string, all bool, op string, ts ...*Term) (string, error) {
+	n, err := cg.RenderingOptions.nativeFieldName(f)
+	if err != nil {
+		return "", err
+	}
+
+	tt, err := cg.RenderingOptions.elementType(f)
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]string, len(ts))
+
+	for i, t := range ts {
+		v, vt, err := cg.emitFieldValue(f, t)
+		if err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, vt); err != nil {
+			return "", err
+		}
+		values[i] = v
+	}
+
+	if (op == "gt" || op == "gte" || op == "lt" || op == "lte") && !isOrdered(tt) {
+		return "", errors.Errorf("cannot compare values of type %s", toTypeName(tt))
+	}
+
+	var cond string
+	switch {
+	case op == "in" && all:
+		cond = fmt.Sprintf(`{"$nin":[%s]}`, strings.Join(values, ","))
+	case op == "in":
+		cond = fmt.Sprintf(`{"$in":[%s]}`, strings.Join(values, ","))
+	case all:
+		cond = fmt.Sprintf(`{"%s":%s}`, toMongoOp(negateOp(op)), values[0])
+	default:
+		cond = fmt.Sprintf(`{"%s":%s}`, toMongoOp(op), values[0])
+	}
+
+	if all {
+		return fmt.Sprintf(`{%s:{"$not":{"$elemMatch":%s}}}`, jsonString(n), cond), nil
+	}
+
+	return fmt.Sprintf(`{%s:{"$elemMatch":%s}}`, jsonString(n), cond), nil
+}
+
+// emitIs renders i.
+func (cg *MongoCodeGenerator) emitIs(i *Is) (string, error) {
+	var f string
+	var s string
+
+	if i.IsWithExplicitValue != nil {
+		f = i.IsWithExplicitValue.Ident
+		switch {
+		case i.IsWithExplicitValue.Value == "null" && i.IsWithExplicitValue.Not:
+			s = `{"$exists":true,"$ne":null}`
+		case i.IsWithExplicitValue.Value == "null":
+			s = `{"$eq":null}`
+		case i.IsWithExplicitValue.Not:
+			s = fmt.Sprintf(`{"$ne":%s}`, i.IsWithExplicitValue.Value)
+		default:
+			s = fmt.Sprintf(`{"$eq":%s}`, i.IsWithExplicitValue.Value)
+		}
+	} else if i.IsWithImplicitValue != nil {
+		f = i.IsWithImplicitValue.Ident
+		s = fmt.Sprintf(`{"$eq":%t}`, !i.IsWithImplicitValue.Not)
+	}
+
+	if i.IsWithImplicitValue != nil || i.IsWithExplicitValue.Value != "null" {
+		if err := cg.RenderingOptions.validateBoolField(f); err != nil {
+			return "", err
+		}
+	}
+
+	n, err := cg.RenderingOptions.nativeFieldName(f)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`{%s:%s}`, jsonString(n), s), nil
+}
+
+// emitAggTermOrMath renders tm as an aggregation expression.
+func (cg *MongoCodeGenerator) emitAggTermOrMath(tm *TermOrMath) (string, termType, error) {
+	s, t, err := cg.emitAggProduct(tm.Product)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	for _, as := range tm.Products {
+		if m := durationOf(as.Product); m != nil {
+			if s, t, err = cg.emitDateAdd(s, t, m, as.Op == "sub"); err != nil {
+				return "", undefType, err
+			}
+			continue
+		}
+
+		s2, t2, err := cg.emitAggProduct(as.Product)
+		if err != nil {
+			return "", undefType, err
+		}
+		if s, t, err = cg.emitAggArithmetic(s, t, as.Op, s2, t2); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	return s, t, nil
+}
+
+// emitAggProduct renders p as an aggregation expression.
+func (cg *MongoCodeGenerator) emitAggProduct(p *Product) (string, termType, error) {
+	s, t, err := cg.emitAggFactor(p.Factor)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	for _, md := range p.Factors {
+		s2, t2, err := cg.emitAggFactor(md.Factor)
+		if err != nil {
+			return "", undefType, err
+		}
+		if s, t, err = cg.emitAggArithmetic(s, t, md.Op, s2, t2); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	return s, t, nil
+}
+
+// emitAggFactor renders f as an aggregation expression. Negated factors are
+// multiplied by -1.
+func (cg *MongoCodeGenerator) emitAggFactor(f *Factor) (string, termType, error) {
+	var err error
+	var s string
+	var t termType
+
+	if f.SubMath != nil {
+		s, t, err = cg.emitAggTermOrMath(f.SubMath)
+	} else if f.Term != nil {
+		s, t, err = cg.emitAggTerm(f.Term)
+	}
+
+	if err != nil || !f.Minus {
+		return s, t, err
+	}
+
+	if t != identType && t != intType && t != decimalType {
+		return "", undefType, errors.Errorf("cannot negate values of type %s", toTypeName(t))
+	}
+
+	return fmt.Sprintf(`{"$multiply":[-1,%s]}`, s), t, nil
+}
+
+// emitAggArithmetic renders the arithmetic operation s1 op s2 as an aggregation
+// expression, where s1 and s2 are of type t1 and t2 respectively.
+func (cg *MongoCodeGenerator) emitAggArithmetic(s1 string, t1 termType, op string, s2 string, t2 termType) (string, termType, error) {
+	t, err := validateTypes(t1, t2)
+	if err != nil {
+		return "", undefType, err
+	} else if t != identType && t != intType && t != decimalType && t != dateType && t != timeType && t != dateTimeType {
+		return "", t, errors.Errorf("cannot compute values of type %s", toTypeName(t))
+	}
+
+	switch op {
+	case "add":
+		op = "$add"
+	case "sub":
+		op = "$subtract"
+	case "mul":
+		op = "$multiply"
+	case "div":
+		op = "$divide"
+	}
+
+	return fmt.Sprintf(`{"%s":[%s,%s]}`, op, s1, s2), t, nil
+}
+
+// emitAggTerm renders t as an aggregation expression. Fields are referenced
+// with the $ prefix, whereas strings are wrapped in $literal so that they are
+// never interpreted as field paths.
+func (cg *MongoCodeGenerator) emitAggTerm(t *Term) (string, termType, error) {
+	if t.Identifier != nil {
+		n, err := cg.RenderingOptions.nativeFieldName(*t.Identifier)
+		if err != nil {
+			return "", undefType, err
+		}
+		return jsonString("$" + n), cg.RenderingOptions.fieldType(*t.Identifier), nil
+	} else if t.String != nil {
+		return fmt.Sprintf(`{"$literal":%s}`, jsonString(*t.String)), stringType, nil
+	} else if t.Function != nil {
+		return cg.emitAggFunction(t.Function)
+	} else if t.Macro != nil && !builtinMacros[t.Macro.Name] {
+		return cg.emitUserMacro(t.Macro, cg.emitAggTerm)
+	}
+
+	return cg.emitValue(t)
+}
+
+// emitAggFunction renders f as an aggregation operator after verifying that its
+// arguments match its signature.
+func (cg *MongoCodeGenerator) emitAggFunction(f *Function) (string, termType, error) {
+	args := make([]string, len(f.Args))
+	types := make([]termType, len(f.Args))
+
+	for i, a := range f.Args {
+		var err error
+		if args[i], types[i], err = cg.emitAggTermOrMath(a); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	t, err := validateCall(f.Name, types)
+	if err != nil {
+		return "", undefType, err
+	} else if f.Name == "hour" && types[0] == timeType {
+		return "", undefType, errors.New("mongo does not support hour on time values since times are rendered as strings")
+	}
+
+	var s string
+	switch op := mongoFunctions[f.Name]; f.Name {
+	case "trim":
+		s = fmt.Sprintf(`{"%s":{"input":%s}}`, op, args[0])
+	case "round", "coalesce":
+		s = fmt.Sprintf(`{"%s":[%s]}`, op, strings.Join(args, ","))
+	default:
+		s = fmt.Sprintf(`{"%s":%s}`, op, args[0])
+	}
+
+	return s, t, nil
+}
+
+// emitValue renders t as a literal value.
+func (cg *MongoCodeGenerator) emitValue(t *Term) (string, termType, error) {
+	var err error
+	var s string
+	var tt termType
+
+	if t.Identifier != nil {
+		err = errors.Errorf("field %s cannot be used as a value", *t.Identifier)
+	} else if t.Function != nil {
+		err = errors.Errorf("function %s cannot be used as a value", t.Function.Name)
+	} else if t.Integer != nil {
+		tt = intType
+		s = strconv.Itoa(*t.Integer)
+	} else if t.Decimal != nil {
+		tt = decimalType
+		s = strconv.FormatFloat(*t.Decimal, 'f', -1, 64)
+	} else if t.String != nil {
+		tt = stringType
+		s = jsonString(*t.String)
+	} else if t.Date != nil {
+		tt = dateType
+		s, err = toMongoDate(*t.Date+"T00:00:00", cg.RenderingOptions.GetLocation())
+	} else if t.Time != nil {
+		tt = timeType
+		if _, err = toValue(*t.Time, tt); err == nil {
+			s = jsonString(*t.Time)
+		}
+	} else if t.DateTime != nil {
+		tt = dateTimeType
+		s, err = toMongoDate(*t.DateTime, cg.RenderingOptions.GetLocation())
+	} else if t.Bool != nil {
+		tt = boolType
+		s = *t.Bool
+	} else if t.Macro != nil {
+		s, tt, err = cg.emitMacro(t.Macro)
+	}
+
+	return s, tt, err
+}
+
+// emitMacro renders m.
+func (cg *MongoCodeGenerator) emitMacro(m *Macro) (string, termType, error) {
+	var err error
+	var s string
+	var t termType
+
+	switch m.Name {
+	case "#now":
+		s, t, err = cg.emitNowMacro(m)
+	case "#duration":
+		s, t, err = cg.emitDurationMacro(m)
+	case "#today", "#startof", "#endof", "#ago":
+		s, t, err = cg.emitCalendarMacro(m)
+	default:
+		s, t, err = cg.emitUserMacro(m, cg.emitValue)
+	}
+
+	return s, t, err
+}
+
+// emitUserMacro renders the user-defined macro m with the renderer for mongo,
+// where the literal the macro expands to is rendered by emit.
+func (cg *MongoCodeGenerator) emitUserMacro(m *Macro, emit func(*Term) (string, termType, error)) (string, termType, error) {
+	e, t, err := cg.RenderingOptions.GetMacroRegistry().expand(m, "mongo")
+	if err != nil {
+		return "", undefType, err
+	} else if nc, ok := e.(NativeCode); ok {
+		return string(nc), t, nil
+	}
+
+	s, _, err := emit(e.(*Term))
+	return s, t, err
+}
+
+// emitCalendarMacro renders m as the date it expands to relative to the clock
+// and time zone of the rendering options.
+func (cg *MongoCodeGenerator) emitCalendarMacro(m *Macro) (string, termType, error) {
+	t, tt, err := expandCalendarMacro(m, cg.RenderingOptions.now())
+	if err != nil {
+		return "", undefType, err
+	}
+
+	s, _, err := cg.emitValue(t)
+	return s, tt, err
+}
+
+// emitNowMacro renders m as the current time according to the clock of the
+// rendering options, if any, or as the current time of the server otherwise.
+func (cg *MongoCodeGenerator) emitNowMacro(m *Macro) (string, termType, error) {
+	if err := expectNoArgs(m); err != nil {
+		return "", undefType, err
+	} else if cg.RenderingOptions.GetClock() != nil {
+		return cg.emitCalendarMacro(m)
+	}
+
+	return `"$$NOW"`, dateTimeType, nil
+}
+
+// emitDurationMacro renders m as a number of milliseconds, which is what
+// MongoDB expects when adding to or subtracting from dates. Durations with
+// years or months can only be added to or subtracted from dates.
+func (cg *MongoCodeGenerator) emitDurationMacro(m *Macro) (string, termType, error) {
+	d, err := toDuration(m)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	ms, err := d.milliseconds()
+	if err != nil {
+		return "", undefType, errors.Wrapf(err, "%s can only be added to or subtracted from a date", m.Name)
+	}
+
+	return strconv.FormatInt(ms, 10), dateTimeType, nil
+}
+
+// emitDateAdd renders the addition of the duration m to s, which is of type t.
+// If sub is true, then the duration is subtracted instead. Durations with years
+// or months are rendered with $dateAdd and $dateSubtract, one per component,
+// since their length varies, and fractional seconds are truncated to
+// milliseconds.
+func (cg *MongoCodeGenerator) emitDateAdd(s string, t termType, m *Macro, sub bool) (string, termType, error) {
+	d, err := toDuration(m)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	if ms, err := d.milliseconds(); err == nil {
+		op := "add"
+		if sub {
+			op = "sub"
+		}
+		return cg.emitAggArithmetic(s, t, op, strconv.FormatInt(ms, 10), dateTimeType)
+	} else if t != identType && t != dateType && t != dateTimeType {
+		return "", undefType, errors.Errorf("cannot add an interval to values of type %s", toTypeName(t))
+	}
+
+	for _, p := range d.parts(false) {
+		p, neg := p.abs()
+		if p.unit == "MICROSECOND" {
+			if p = (durationPart{p.value / 1000, "MILLISECOND", 0}); p.value == 0 {
+				continue
+			}
+		}
+
+		op := "$dateAdd"
+		if sub != neg {
+			op = "$dateSubtract"
+		}
+		s = fmt.Sprintf(`{"%s":{"startDate":%s,"unit":%s,"amount":%d}}`, op, s, jsonString(strings.ToLower(p.unit)), p.value)
+	}
+
+	if t == identType {
+		t = dateTimeType
+	}
+
+	return s, t, nil
+}
+
+// toMongoOp returns the MongoDB operator that corresponds to op.
+func toMongoOp(op string) string {
+	var s string
+
+	switch op {
+	case "eq":
+		s = "$eq"
+	case "neq":
+		s = "$ne"
+	case "gt":
+		s = "$gt"
+	case "gte":
+		s = "$gte"
+	case "lt":
+		s = "$lt"
+	case "lte":
+		s = "$lte"
+	}
+
+	return s
+}
+
+// toMongoDate renders the ISO-8601 timestamp s as an Extended JSON date, where
+// timestamps without offset are interpreted in loc.
+func toMongoDate(s string, loc *time.Location) (string, error) {
+	v, err := toValueIn(s, dateTimeType, loc)
+	if err != nil {
+		return "", errors.Errorf("invalid timestamp %s", s)
+	}
+
+	return fmt.Sprintf(`{"$date":%s}`, jsonString(v.(time.Time).UTC().Format("2006-01-02T15:04:05.999Z07:00"))), nil
+}
+
+// jsonString returns s as a JSON string.
+func jsonString(s string) string {
+	var sb strings.Builder
+
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
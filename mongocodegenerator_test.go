@@ -0,0 +1,145 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestGenerateMongo tests the generation of MongoDB filters from Espresso++
+// expressions.
+func TestGenerateMongo(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewMongoCodeGenerator()
+	codeGenerator.RenderingOptions.AddFieldProps("mapped", &FieldProps{Filterable: true, NativeName: "native"})
+	codeGenerator.RenderingOptions.AddFieldProps("hidden", &FieldProps{Filterable: false})
+	codeGenerator.RenderingOptions.AddFieldProps("restricted", &FieldProps{Filterable: true, Operators: []string{"eq", "in"}})
+	codeGenerator.RenderingOptions.AddFieldProps("computed", &FieldProps{Filterable: true, NativeExpr: "first || ' ' || last"})
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+	for _, item := range getMongoTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateMongoWithTypedFields tests the generation of MongoDB filters from
+// Espresso++ expressions that reference fields with a declared type or a set of
+// allowed values.
+func TestGenerateMongoWithTypedFields(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewMongoCodeGenerator()
+	codeGenerator.RenderingOptions.Fields(getTypedFields())
+
+	for _, item := range getMongoTypedFieldsTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateMongoWithClock tests the generation of MongoDB filters from
+// Espresso++ expressions whose macros and literals are resolved relative to a
+// clock and time zone.
+func TestGenerateMongoWithClock(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewMongoCodeGenerator()
+	codeGenerator.RenderingOptions.SetClock(getClock())
+	codeGenerator.RenderingOptions.SetLocation(getLocation())
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+	for _, item := range getClockTestDataItems()["mongo"] {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateMongoWithoutRenderingOptions tests the generation of MongoDB
+// filters with a code generator that has no rendering options.
+func TestGenerateMongoWithoutRenderingOptions(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := &MongoCodeGenerator{}
+
+	for _, item := range []testDataItem{
+		{"ident eq 'text' and ident2 gt 10", `{"$and":[{"ident":{"$eq":"text"}},{"ident2":{"$gt":10}}]}`, false},
+		{"ident eq '2020-03-15T14:10:25'", `{"ident":{"$eq":{"$date":"2020-03-15T14:10:25Z"}}}`, false},
+		{"ident lt #now", `{"$expr":{"$lt":["$ident","$$NOW"]}}`, false},
+		{"ident eq #undefined", "", true},
+	} {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
@@ -16,15 +16,22 @@ import (
 )
 
 type Term struct {
-	Identifier *string  `  @Ident`
-	Integer    *int     `| @Int`
-	Decimal    *float64 `| @Float`
-	String     *string  `| @String`
-	Date       *string  `| @Date`
-	Time       *string  `| @Time`
-	DateTime   *string  `| @DateTime`
-	Bool       *string  `| @Bool`
-	Macro      *Macro   `| @@`
+	Function   *Function `  @@`
+	Identifier *string   `| @Ident`
+	Integer    *int      `| @Int`
+	Decimal    *float64  `| @Float`
+	String     *string   `| @String`
+	Date       *string   `| @Date`
+	Time       *string   `| @Time`
+	DateTime   *string   `| @DateTime`
+	Bool       *string   `| @Bool`
+	Macro      *Macro    `| @@`
+}
+
+// Function is a call of a builtin scalar function, e.g. lower(name).
+type Function struct {
+	Name string        `@Ident "("`
+	Args []*TermOrMath `(@@ ("," @@)*)? ")"`
 }
 
 type Macro struct {
@@ -32,16 +39,37 @@ type Macro struct {
 	Args []*Term `("(" (@@ ("," @@)*)? ")")?`
 }
 
-type Math struct {
-	Term1 *Term  `@@`
-	Op    string `@("add" | "sub" | "mul" | "div")`
-	Term2 *Term  `@@`
+// Factor is an operand of mul and div, optionally negated.
+type Factor struct {
+	Minus   bool        `@"-"?`
+	SubMath *TermOrMath `( "(" @@ ")"`
+	Term    *Term       `| @@ )`
+}
+
+// MulDiv is a factor preceded by either mul or div.
+type MulDiv struct {
+	Op     string  `@("mul" | "div")`
+	Factor *Factor `@@`
+}
+
+// Product is a sequence of factors separated by mul or div.
+type Product struct {
+	Factor  *Factor   `@@`
+	Factors []*MulDiv `@@*`
+}
+
+// AddSub is a product preceded by either add or sub.
+type AddSub struct {
+	Op      string   `@("add" | "sub")`
+	Product *Product `@@`
 }
 
+// TermOrMath is either a single term or an arithmetic expression, i.e. a
+// sequence of products separated by add or sub, so that mul and div bind
+// tighter than add and sub.
 type TermOrMath struct {
-	Math    *Math `  @@`
-	SubMath *Math `| "(" @@ ")"`
-	Term    *Term `| @@`
+	Product  *Product  `@@`
+	Products []*AddSub `@@*`
 }
 
 type Equality struct {
@@ -66,10 +94,34 @@ type Range struct {
 
 type Match struct {
 	Term1 *Term  `@@`
-	Op    string `@("startswith" | "endswith" | "contains")`
+	Op    string `@("startswith" | "endswith" | "contains" | "istartswith" | "iendswith" | "icontains" | "ieq" | "matches")`
 	Term2 *Term  `@@`
 }
 
+// In is the membership test of a value in a list of terms.
+type In struct {
+	TermOrMath *TermOrMath `@@`
+	Not        bool        `@("not")? "in"`
+	Terms      []*Term     `"(" @@ ("," @@)* ")"`
+}
+
+// Has is the test of whether an array field contains a term.
+type Has struct {
+	Field string `@Ident "has"`
+	Term  *Term  `@@`
+}
+
+// Quantified is the test of whether any or all the elements of an array field
+// either compare with a term or are in a list of terms.
+type Quantified struct {
+	Field      string  `@Ident`
+	Quantifier string  `@("any" | "all")`
+	Op         string  `( @("eq" | "neq" | "gt" | "gte" | "lt" | "lte")`
+	Term       *Term   `  @@`
+	In         bool    `| @"in"`
+	Terms      []*Term `  "(" @@ ("," @@)* ")" )`
+}
+
 type Is struct {
 	IsWithExplicitValue *IsWithExplicitValue `  @@`
 	IsWithImplicitValue *IsWithImplicitValue `| @@`
@@ -87,24 +139,235 @@ type IsWithImplicitValue struct {
 }
 
 type SubExpression struct {
-	Not         bool          `@("not")?`
-	Expressions []*Expression `"(" @@+ ")"`
+	Not         bool         `@("not")?`
+	Disjunction *Disjunction `"(" @@ ")"`
 }
 
 type Expression struct {
-	Op            *string        `  @("and" | "or")`
-	SubExpression *SubExpression `| @@`
+	SubExpression *SubExpression `  @@`
 	Comparison    *Comparison    `| @@`
 	Equality      *Equality      `| @@`
 	Range         *Range         `| @@`
 	Match         *Match         `| @@`
+	In            *In            `| @@`
+	Has           *Has           `| @@`
+	Quantified    *Quantified    `| @@`
 	Is            *Is            `| @@`
 }
 
+// Conjunction is a list of expressions separated by and.
+type Conjunction struct {
+	Expressions []*Expression `@@ ("and" @@)*`
+}
+
+// Disjunction is a list of conjunctions separated by or, so that and binds
+// tighter than or.
+type Disjunction struct {
+	Conjunctions []*Conjunction `@@ ("or" @@)*`
+}
+
 // Grammar is the set of structural rules that govern the composition of an
 // Espesso++ expression.
 type Grammar struct {
-	Expressions []*Expression `@@+`
+	Disjunction *Disjunction `@@`
+}
+
+// termOf returns the term tm consists of, or nil if tm is an arithmetic
+// expression.
+func termOf(tm *TermOrMath) *Term {
+	if len(tm.Products) > 0 {
+		return nil
+	}
+
+	return factorTermOf(tm.Product)
+}
+
+// factorTermOf returns the term p consists of, or nil if p is a product of
+// factors, a negated factor, or a parenthesized arithmetic expression.
+func factorTermOf(p *Product) *Term {
+	if len(p.Factors) > 0 || p.Factor.Minus {
+		return nil
+	}
+
+	return p.Factor.Term
+}
+
+// durationOf returns the #duration macro p consists of, or nil if p is anything
+// else.
+func durationOf(p *Product) *Macro {
+	if t := factorTermOf(p); t != nil && t.Macro != nil && t.Macro.Name == "#duration" {
+		return t.Macro
+	}
+
+	return nil
+}
+
+// isField returns a Boolean value indicating whether or not tm is a field.
+func isField(tm *TermOrMath) bool {
+	t := termOf(tm)
+	return t != nil && t.Identifier != nil
+}
+
+// isLiteral returns a Boolean value indicating whether or not tm is a literal
+// value that can be compared with a field without an aggregation expression.
+func isLiteral(tm *TermOrMath) bool {
+	t := termOf(tm)
+	return t != nil && t.Identifier == nil && t.Macro == nil && t.Function == nil
+}
+
+// matchOp returns the case-sensitive counterpart of the match operator op,
+// along with a Boolean value indicating whether or not op ignores case, e.g.
+// contains for icontains.
+func matchOp(op string) (string, bool) {
+	switch op {
+	case "istartswith", "iendswith", "icontains", "ieq":
+		return op[1:], true
+	}
+
+	return op, false
+}
+
+// walkPredicates calls f for every predicate in d with the operator of the
+// predicate and the fields its operands reference, and stops at the first error.
+// Membership tests are reported as in, whether negated or not, Boolean tests as
+// is, and tests on the elements of arrays as either any or all.
+func walkPredicates(d *Disjunction, f func(op string, fields []string) error) error {
+	for _, c := range d.Conjunctions {
+		for _, e := range c.Expressions {
+			var err error
+
+			if e.SubExpression != nil {
+				err = walkPredicates(e.SubExpression.Disjunction, f)
+			} else if e.Comparison != nil {
+				err = f(e.Comparison.Op, fieldsOf(e.Comparison.TermOrMath1, e.Comparison.TermOrMath2))
+			} else if e.Equality != nil {
+				err = f(e.Equality.Op, fieldsOf(e.Equality.TermOrMath1, e.Equality.TermOrMath2))
+			} else if e.Range != nil {
+				err = f(e.Range.Between, fieldsOf(e.Range.TermOrMath1, e.Range.TermOrMath2, e.Range.TermOrMath3))
+			} else if e.Match != nil {
+				err = f(e.Match.Op, termFieldsOf(nil, e.Match.Term1, e.Match.Term2))
+			} else if e.In != nil {
+				err = f("in", termFieldsOf(fieldsOf(e.In.TermOrMath), e.In.Terms...))
+			} else if e.Has != nil {
+				err = f("has", termFieldsOf([]string{e.Has.Field}, e.Has.Term))
+			} else if e.Quantified != nil {
+				q := e.Quantified
+				err = f(q.Quantifier, termFieldsOf([]string{q.Field}, q.terms()...))
+			} else if e.Is != nil {
+				if e.Is.IsWithExplicitValue != nil {
+					err = f("is", []string{e.Is.IsWithExplicitValue.Ident})
+				} else {
+					err = f("is", []string{e.Is.IsWithImplicitValue.Ident})
+				}
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldsOf returns the fields referenced by tms.
+func fieldsOf(tms ...*TermOrMath) []string {
+	var fields []string
+
+	var factor func(f *Factor)
+	product := func(p *Product) {
+		factor(p.Factor)
+		for _, md := range p.Factors {
+			factor(md.Factor)
+		}
+	}
+	factor = func(f *Factor) {
+		if f.SubMath != nil {
+			fields = append(fields, fieldsOf(f.SubMath)...)
+		} else {
+			fields = termFieldsOf(fields, f.Term)
+		}
+	}
+
+	for _, tm := range tms {
+		product(tm.Product)
+		for _, as := range tm.Products {
+			product(as.Product)
+		}
+	}
+
+	return fields
+}
+
+// termFieldsOf appends the fields referenced by ts, including macro and function
+// arguments, to fields and returns the resulting slice.
+func termFieldsOf(fields []string, ts ...*Term) []string {
+	for _, t := range ts {
+		if t.Identifier != nil {
+			fields = append(fields, *t.Identifier)
+		} else if t.Macro != nil {
+			fields = termFieldsOf(fields, t.Macro.Args...)
+		} else if t.Function != nil {
+			fields = append(fields, fieldsOf(t.Function.Args...)...)
+		}
+	}
+
+	return fields
+}
+
+// terms returns the terms q compares the elements of the array with.
+func (q *Quantified) terms() []*Term {
+	if q.In {
+		return q.Terms
+	}
+
+	return []*Term{q.Term}
+}
+
+// op returns the operator q applies to the elements of the array, i.e. either
+// in or a comparison operator.
+func (q *Quantified) op() string {
+	if q.In {
+		return "in"
+	}
+
+	return q.Op
+}
+
+// negateOp returns the operator that yields the opposite result of op.
+func negateOp(op string) string {
+	switch op {
+	case "eq":
+		op = "neq"
+	case "neq":
+		op = "eq"
+	case "gt":
+		op = "lte"
+	case "gte":
+		op = "lt"
+	case "lt":
+		op = "gte"
+	case "lte":
+		op = "gt"
+	}
+
+	return op
+}
+
+// mirrorOp returns the operator to be used when the operands of op are swapped.
+func mirrorOp(op string) string {
+	switch op {
+	case "gt":
+		op = "lt"
+	case "gte":
+		op = "lte"
+	case "lt":
+		op = "gt"
+	case "lte":
+		op = "gte"
+	}
+
+	return op
 }
 
 // parser is the part of an interpreter that attaches meaning by classifying strings
@@ -114,14 +377,21 @@ type parser struct {
 	espressoppParser *participle.Parser
 }
 
+const (
+	// maxLookahead is the maximum number of tokens the parser looks ahead to
+	// choose between alternatives, e.g. between a parenthesized condition and
+	// a parenthesized arithmetic expression.
+	maxLookahead = 256
+)
+
 var (
 	espressoppLexer = lexer.Must(ebnf.New(`
 		Comment = "//" { "\u0000"…"\uffff"-"\n" } .
 		Date = "\"" date "\"" | "'" date "'" .
 		Time = "\"" time "\"" | "'" time "'" .
-		DateTime = "\"" date "T" time [ "+" digit digit ] "\"" | "'" date "T" time [ "+" digit digit  ] "'" .
+		DateTime = "\"" date ( "T" | "t" ) time [ offset ] "\"" | "'" date ( "T" | "t" ) time [ offset ] "'" .
 		Bool = "true" | "false" .
-		Ident = ident .
+		Ident = path .
 		Macro = "#" ident .
 		String = "\"" { "\u0000"…"\uffff"-"\""-"\\" | "\\" any } "\"" | "'" { "\u0000"…"\uffff"-"'"-"\\" | "\\" any } "'" .
 		Int = [ "-" | "+" ] digit { digit } .
@@ -133,8 +403,10 @@ var (
 		digit = "0"…"9" .
 		any = "\u0000"…"\uffff" .
 		ident = (alpha | "_") { "_" | alpha | digit } .
+		path = ident { "." ident } .
 		date = digit digit digit digit "-" digit digit "-" digit digit .
 		time = digit digit ":" digit digit ":" digit digit [ "." { digit } ] .
+		offset = "Z" | "z" | ( "+" | "-" ) digit digit [ ":" digit digit ] .
 	`))
 )
 
@@ -145,7 +417,7 @@ func newParser() *parser {
 			participle.Lexer(espressoppLexer),
 			participle.Unquote("String", "Date", "Time", "DateTime"),
 			participle.Elide("Whitespace", "Comment"),
-			participle.UseLookahead(2)),
+			participle.UseLookahead(maxLookahead)),
 	}
 }
 
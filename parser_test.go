@@ -19,7 +19,16 @@ func TestParse(t *testing.T) {
 
 	for _, item := range getTestDataItems() {
 		r := strings.NewReader(item.input)
-		grammar, _ := parser.parse(r)
+		grammar, err := parser.parse(r)
+
+		if err != nil {
+			if item.hasError {
+				t.Logf("Parser with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			} else {
+				t.Errorf("Parser with input '%v' : FAILED, %v", item.input, err)
+			}
+			continue
+		}
 
 		result := emitGrammar(grammar)
 
@@ -31,24 +40,57 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// TestParsePrecedence tests that and binds tighter than or.
+func TestParsePrecedence(t *testing.T) {
+	parser := newParser()
+
+	r := strings.NewReader("ident1 eq 1 or ident2 eq 2 and not (ident3 eq 3 or ident4 eq 4)")
+	grammar, err := parser.parse(r)
+	if err != nil {
+		t.Fatalf("Parser : FAILED, %v", err)
+	}
+
+	d := grammar.Disjunction
+	if len(d.Conjunctions) != 2 || len(d.Conjunctions[0].Expressions) != 1 || len(d.Conjunctions[1].Expressions) != 2 {
+		t.Fatalf("Parser : FAILED, unexpected parse tree %v", parser.string(grammar))
+	}
+
+	se := d.Conjunctions[1].Expressions[1].SubExpression
+	if se == nil || !se.Not || len(se.Disjunction.Conjunctions) != 2 {
+		t.Errorf("Parser : FAILED, unexpected parse tree %v", parser.string(grammar))
+	}
+}
+
 // emitGrammars renders the expressions in g.
 func emitGrammar(g *Grammar) string {
-	var sb strings.Builder
+	return emitDisjunction(g.Disjunction)
+}
 
-	for _, e := range g.Expressions {
-		sb.WriteString(emitExpression(e))
+// emitDisjunction renders d.
+func emitDisjunction(d *Disjunction) string {
+	conjunctions := make([]string, len(d.Conjunctions))
+	for i, c := range d.Conjunctions {
+		conjunctions[i] = emitConjunction(c)
 	}
 
-	return sb.String()
+	return strings.Join(conjunctions, " or ")
+}
+
+// emitConjunction renders c.
+func emitConjunction(c *Conjunction) string {
+	expressions := make([]string, len(c.Expressions))
+	for i, e := range c.Expressions {
+		expressions[i] = emitExpression(e)
+	}
+
+	return strings.Join(expressions, " and ")
 }
 
 // emitExpression renders e.
 func emitExpression(e *Expression) string {
 	var s string
 
-	if e.Op != nil {
-		s = fmt.Sprintf(" %s ", *e.Op)
-	} else if e.SubExpression != nil {
+	if e.SubExpression != nil {
 		s = emitSubExpression(e.SubExpression)
 	} else if e.Comparison != nil {
 		s = emitComparison(e.Comparison)
@@ -58,6 +100,12 @@ func emitExpression(e *Expression) string {
 		s = emitRange(e.Range)
 	} else if e.Match != nil {
 		s = emitMatch(e.Match)
+	} else if e.In != nil {
+		s = emitIn(e.In)
+	} else if e.Has != nil {
+		s = emitHas(e.Has)
+	} else if e.Quantified != nil {
+		s = emitQuantified(e.Quantified)
 	} else if e.Is != nil {
 		s = emitIs(e.Is)
 	}
@@ -74,11 +122,7 @@ func emitSubExpression(se *SubExpression) string {
 	}
 
 	sb.WriteString("(")
-
-	for _, e := range se.Expressions {
-		sb.WriteString(emitExpression(e))
-	}
-
+	sb.WriteString(emitDisjunction(se.Disjunction))
 	sb.WriteString(")")
 
 	return sb.String()
@@ -117,6 +161,40 @@ func emitMatch(m *Match) string {
 	return fmt.Sprintf("%s %s %s", t1, m.Op, t2)
 }
 
+// emitIn renders i.
+func emitIn(i *In) string {
+	terms := make([]string, len(i.Terms))
+	for j, t := range i.Terms {
+		terms[j] = emitTerm(t)
+	}
+
+	op := "in"
+	if i.Not {
+		op = "not in"
+	}
+
+	return fmt.Sprintf("%s %s (%s)", emitTermOrMath(i.TermOrMath), op, strings.Join(terms, ", "))
+}
+
+// emitHas renders h.
+func emitHas(h *Has) string {
+	return fmt.Sprintf("%s has %s", h.Field, emitTerm(h.Term))
+}
+
+// emitQuantified renders q.
+func emitQuantified(q *Quantified) string {
+	if !q.In {
+		return fmt.Sprintf("%s %s %s %s", q.Field, q.Quantifier, q.Op, emitTerm(q.Term))
+	}
+
+	terms := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		terms[i] = emitTerm(t)
+	}
+
+	return fmt.Sprintf("%s %s in (%s)", q.Field, q.Quantifier, strings.Join(terms, ", "))
+}
+
 // emitIs renders i.
 func emitIs(i *Is) string {
 	var sb strings.Builder
@@ -162,26 +240,30 @@ func emitMacro(m *Macro) string {
 	return sb.String()
 }
 
-// emitMath renders m.
-func emitMath(m *Math) string {
-	t1 := emitTerm(m.Term1)
-	t2 := emitTerm(m.Term2)
+// emitFunction renders f.
+func emitFunction(f *Function) string {
+	args := make([]string, len(f.Args))
+	for i, a := range f.Args {
+		args[i] = emitTermOrMath(a)
+	}
 
-	return fmt.Sprintf("%s %s %s", t1, m.Op, t2)
+	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(args, ", "))
 }
 
 // emitTerm renders t.
 func emitTerm(t *Term) string {
 	var s string
 
-	if t.Identifier != nil {
+	if t.Function != nil {
+		s = emitFunction(t.Function)
+	} else if t.Identifier != nil {
 		s = *t.Identifier
 	} else if t.Integer != nil {
 		s = strconv.Itoa(*t.Integer)
 	} else if t.Decimal != nil {
 		s = strconv.FormatFloat(*t.Decimal, 'f', -1, 64)
 	} else if t.String != nil {
-		s = fmt.Sprintf("'%s'", *t.String)
+		s = fmt.Sprintf("'%s'", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(*t.String))
 	} else if t.Date != nil {
 		s = fmt.Sprintf("'%s'", *t.Date)
 	} else if t.Time != nil {
@@ -199,14 +281,42 @@ func emitTerm(t *Term) string {
 
 // emitTermOrMath renders tm.
 func emitTermOrMath(tm *TermOrMath) string {
+	var sb strings.Builder
+
+	sb.WriteString(emitProduct(tm.Product))
+
+	for _, as := range tm.Products {
+		sb.WriteString(fmt.Sprintf(" %s %s", as.Op, emitProduct(as.Product)))
+	}
+
+	return sb.String()
+}
+
+// emitProduct renders p.
+func emitProduct(p *Product) string {
+	var sb strings.Builder
+
+	sb.WriteString(emitFactor(p.Factor))
+
+	for _, md := range p.Factors {
+		sb.WriteString(fmt.Sprintf(" %s %s", md.Op, emitFactor(md.Factor)))
+	}
+
+	return sb.String()
+}
+
+// emitFactor renders f.
+func emitFactor(f *Factor) string {
 	var s string
 
-	if tm.Math != nil {
-		s = emitMath(tm.Math)
-	} else if tm.SubMath != nil {
-		s = fmt.Sprintf("(%s)", emitMath(tm.SubMath))
-	} else if tm.Term != nil {
-		s = emitTerm(tm.Term)
+	if f.SubMath != nil {
+		s = fmt.Sprintf("(%s)", emitTermOrMath(f.SubMath))
+	} else if f.Term != nil {
+		s = emitTerm(f.Term)
+	}
+
+	if f.Minus {
+		s = "-" + s
 	}
 
 	return s
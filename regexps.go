@@ -0,0 +1,97 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"regexp/syntax"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// regexpFeature is a feature of Go regular expressions that is not supported
+// by every target engine.
+type regexpFeature int
+
+const (
+	// wordBoundaries denotes \b and \B.
+	wordBoundaries regexpFeature = 1 << iota
+
+	// lineAnchors denotes ^ and $ in multi-line mode, i.e. with flag m.
+	lineAnchors
+
+	// nonGreedy denotes non-greedy repetitions like *? and +?, as well as
+	// flag U.
+	nonGreedy
+
+	// caseFolding denotes case-insensitive matching, i.e. flag i.
+	caseFolding
+
+	// namedGroups denotes named capturing groups like (?P<name>re).
+	namedGroups
+)
+
+// regexpFeatureNames maps any regexpFeature to its description.
+var regexpFeatureNames = map[regexpFeature]string{
+	wordBoundaries: "word boundaries",
+	lineAnchors:    "multi-line anchors",
+	nonGreedy:      "non-greedy repetitions",
+	caseFolding:    "case-insensitive flags",
+	namedGroups:    "named groups",
+}
+
+// validateRegexp verifies whether or not p is a valid regular expression in Go
+// syntax that does not use any of the features in unsupported, and if it is
+// not, it returns an error that mentions engine.
+func validateRegexp(p string, unsupported regexpFeature, engine string) error {
+	re, err := syntax.Parse(p, syntax.Perl)
+	if err != nil {
+		return errors.Wrapf(err, "invalid regular expression %s", p)
+	}
+
+	if f := regexpFeaturesOf(re) & unsupported; f != 0 {
+		var names []string
+		for feature := wordBoundaries; feature <= namedGroups; feature <<= 1 {
+			if f&feature != 0 {
+				names = append(names, regexpFeatureNames[feature])
+			}
+		}
+		return errors.Errorf("%s does not support %s in regular expressions", engine, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// regexpFeaturesOf returns the features re and its subexpressions use.
+func regexpFeaturesOf(re *syntax.Regexp) regexpFeature {
+	var f regexpFeature
+
+	switch re.Op {
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		f |= wordBoundaries
+	case syntax.OpBeginLine, syntax.OpEndLine:
+		f |= lineAnchors
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if re.Flags&syntax.NonGreedy != 0 {
+			f |= nonGreedy
+		}
+	case syntax.OpLiteral, syntax.OpCharClass:
+		if re.Flags&syntax.FoldCase != 0 {
+			f |= caseFolding
+		}
+	case syntax.OpCapture:
+		if re.Name != "" {
+			f |= namedGroups
+		}
+	}
+
+	for _, sub := range re.Sub {
+		f |= regexpFeaturesOf(sub)
+	}
+
+	return f
+}
@@ -6,7 +6,16 @@
 
 package espressopp
 
-import "github.com/pkg/errors"
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 // FieldProps is the set of properties associated with a field.
 type FieldProps struct {
@@ -18,6 +27,211 @@ type FieldProps struct {
 	// NativeName is used to map those fields in an input expression that do
 	// not match the field names of the underlying database.
 	NativeName string
+
+	// NativeExpr is the SQL expression a computed field stands for, e.g.
+	// first_name || ' ' || last_name. If specified, SqlCodeGenerator renders
+	// it in parentheses in place of the field, whereas any other CodeGenerator
+	// implementation rejects the field. NativeExpr is trusted as is and must
+	// never be derived from user input.
+	NativeExpr string
+
+	// Type is the declared type of the field. If specified, SqlCodeGenerator
+	// verifies that the field is only compared with values of the same type.
+	Type FieldType
+
+	// Array specifies whether or not the field is an array, in which case Type
+	// and Values apply to its elements. Array fields can only be used with has,
+	// any, and all.
+	Array bool
+
+	// Operators is the set of operators the field can be used with, e.g. eq,
+	// between, contains, in, or is. If empty, then all operators are allowed.
+	Operators []string
+
+	// Values maps the values the field can be compared with to their native
+	// representation, e.g. "active" to 1, which makes the field an enumeration.
+	// Native values are of type int, int64, float64, bool, or string, and nil
+	// native values are rendered as is. If empty, then any value is allowed.
+	Values map[string]interface{}
+}
+
+// PathProps is the set of properties associated with a path prefix, i.e. the
+// leading segments of dotted field paths like address.city. Either JsonColumn
+// or TableAlias must be specified.
+type PathProps struct {
+	// JsonColumn is the JSON column whose keys are denoted by the remaining
+	// segments of the path, e.g. data for data.address.city.
+	JsonColumn string
+
+	// TableAlias is the alias of the joined table whose column is denoted by
+	// the remaining segment of the path, e.g. a for address.city.
+	TableAlias string
+}
+
+// FieldType specifies the type of a field.
+type FieldType int
+
+const (
+	// UntypedField denotes a field that is compatible with any type.
+	UntypedField FieldType = iota
+
+	// IntField denotes an integer field.
+	IntField
+
+	// DecimalField denotes a decimal field, which is also compatible with
+	// integer values.
+	DecimalField
+
+	// StringField denotes a string field.
+	StringField
+
+	// DateField denotes a date field.
+	DateField
+
+	// TimeField denotes a time field.
+	TimeField
+
+	// DateTimeField denotes a timestamp field, which is also compatible with
+	// date values.
+	DateTimeField
+
+	// BoolField denotes a Boolean field.
+	BoolField
+
+	// UuidField denotes a UUID field, which is compatible with string values.
+	UuidField
+
+	// EnumField denotes an enumerated field, which is compatible with string
+	// values.
+	EnumField
+)
+
+// GetFieldType returns the field type with the specified name, i.e. one of
+// int, decimal, string, date, time, datetime, bool, uuid, or enum.
+func GetFieldType(name string) (FieldType, error) {
+	var ft FieldType
+
+	switch strings.ToLower(name) {
+	case "":
+		ft = UntypedField
+	case "int":
+		ft = IntField
+	case "decimal":
+		ft = DecimalField
+	case "string":
+		ft = StringField
+	case "date":
+		ft = DateField
+	case "time":
+		ft = TimeField
+	case "datetime":
+		ft = DateTimeField
+	case "bool":
+		ft = BoolField
+	case "uuid":
+		ft = UuidField
+	case "enum":
+		ft = EnumField
+	default:
+		return ft, errors.Errorf("field type %v not supported", name)
+	}
+
+	return ft, nil
+}
+
+// termType returns the type of the values ft is compatible with.
+func (ft FieldType) termType() termType {
+	switch ft {
+	case IntField:
+		return intType
+	case DecimalField:
+		return decimalType
+	case StringField:
+		return stringType
+	case DateField:
+		return dateType
+	case TimeField:
+		return timeType
+	case DateTimeField:
+		return dateTimeType
+	case BoolField:
+		return boolType
+	case UuidField:
+		return uuidType
+	case EnumField:
+		return enumType
+	}
+
+	return identType
+}
+
+// NamedParam is a named parameter present in rendered code. Value is of type
+// int64, float64, bool, time.Time, or string, depending on the type of the
+// literal the parameter replaces. Times of day are rendered as strings.
+type NamedParam struct {
+	Name  string
+	Value interface{}
+}
+
+// PlaceholderStyle specifies how named parameters are rendered in SQL.
+type PlaceholderStyle int
+
+const (
+	// DialectPlaceholders renders placeholders as required by the SQL dialect.
+	DialectPlaceholders PlaceholderStyle = iota
+
+	// ColonPlaceholders renders placeholders as :P1, :P2, and so on.
+	ColonPlaceholders
+
+	// DollarPlaceholders renders placeholders as $1, $2, and so on.
+	DollarPlaceholders
+
+	// QuestionPlaceholders renders placeholders as ?.
+	QuestionPlaceholders
+
+	// AtPlaceholders renders placeholders as @P1, @P2, and so on.
+	AtPlaceholders
+)
+
+// GetPlaceholderStyle returns the placeholder style with the specified name,
+// i.e. one of dialect, colon, dollar, question, or at.
+func GetPlaceholderStyle(name string) (PlaceholderStyle, error) {
+	var ps PlaceholderStyle
+
+	switch strings.ToLower(name) {
+	case "", "dialect":
+		ps = DialectPlaceholders
+	case "colon":
+		ps = ColonPlaceholders
+	case "dollar":
+		ps = DollarPlaceholders
+	case "question":
+		ps = QuestionPlaceholders
+	case "at":
+		ps = AtPlaceholders
+	default:
+		return ps, errors.Errorf("placeholder style %v not supported", name)
+	}
+
+	return ps, nil
+}
+
+// placeholder renders the placeholder of the named parameter with the specified
+// name and position, or returns false if placeholders are rendered as required
+// by the SQL dialect.
+func (ps PlaceholderStyle) placeholder(name string, pos int) (string, bool) {
+	switch ps {
+	case ColonPlaceholders:
+		return ":" + name, true
+	case DollarPlaceholders:
+		return fmt.Sprintf("$%d", pos), true
+	case QuestionPlaceholders:
+		return "?", true
+	case AtPlaceholders:
+		return "@" + name, true
+	}
+
+	return "", false
 }
 
 // namedParams lets code generators render named parameters and set aside their
@@ -29,15 +243,22 @@ type namedParams struct {
 	// prefix specifies the string that is prepended to parameter names.
 	prefix string
 
-	// values contains the values of the named parameters present in rendered
-	// code.
-	values map[string]string
+	// style specifies how placeholders are rendered.
+	style PlaceholderStyle
+
+	// params contains the named parameters present in rendered code, in the
+	// order they appear.
+	params []NamedParam
 }
 
 // RenderingOptions is the set of options used by CodeGenerator implementations
 // to control the way target code is generated.
 type RenderingOptions struct {
 	fields      map[string]*FieldProps
+	paths       map[string]*PathProps
+	macros      *MacroRegistry
+	clock       func() time.Time
+	location    *time.Location
 	namedParams *namedParams
 }
 
@@ -49,6 +270,7 @@ const (
 func NewRenderingOptions() *RenderingOptions {
 	return &RenderingOptions{
 		fields: make(map[string]*FieldProps),
+		paths:  make(map[string]*PathProps),
 		namedParams: &namedParams{
 			prefix: defaultPrefix,
 		},
@@ -56,24 +278,28 @@ func NewRenderingOptions() *RenderingOptions {
 }
 
 // Clone performs a shallow copy of read-only data and a deep copy of
-// read-write data. Read-only data includes field properties whereas
-// read-write data includes named parameters.
+// read-write data. Read-only data includes field and path properties, the macro
+// registry, as well as clock and location, whereas read-write data includes
+// named parameters.
 func (ro *RenderingOptions) Clone() *RenderingOptions {
-	var m map[string]string
+	var p []NamedParam
 
-	if ro.namedParams.values != nil {
-		m = make(map[string]string)
-		for k, v := range ro.namedParams.values {
-			m[k] = v
-		}
+	if ro.namedParams.params != nil {
+		p = make([]NamedParam, len(ro.namedParams.params))
+		copy(p, ro.namedParams.params)
 	}
 
 	return &RenderingOptions{
-		fields: ro.fields,
+		fields:   ro.fields,
+		paths:    ro.paths,
+		macros:   ro.macros,
+		clock:    ro.clock,
+		location: ro.location,
 		namedParams: &namedParams{
 			enabled: ro.namedParams.enabled,
 			prefix:  ro.namedParams.prefix,
-			values:  m,
+			style:   ro.namedParams.style,
+			params:  p,
 		},
 	}
 }
@@ -94,6 +320,11 @@ func (ro *RenderingOptions) Fields(m map[string]*FieldProps) *RenderingOptions {
 			ro.fields[k] = &FieldProps{
 				Filterable: v.Filterable,
 				NativeName: v.NativeName,
+				NativeExpr: v.NativeExpr,
+				Type:       v.Type,
+				Array:      v.Array,
+				Operators:  v.Operators,
+				Values:     v.Values,
 			}
 		}
 	}
@@ -160,18 +391,98 @@ func (ro *RenderingOptions) GetFieldProps(fieldName string) *FieldProps {
 	return ro.fields[fieldName]
 }
 
+// AddPathProps adds the properties of the specified path prefix to the
+// rendering options, e.g. to map data.address.city to the city key of the
+// address key of JSON column data with prefix data.
+func (ro *RenderingOptions) AddPathProps(prefix string, pp *PathProps) error {
+	if len(prefix) == 0 {
+		return errors.New("path prefix not specified")
+	}
+
+	if pp == nil {
+		return errors.Errorf("properties for path prefix %v not specified", prefix)
+	}
+
+	if (len(pp.JsonColumn) == 0) == (len(pp.TableAlias) == 0) {
+		return errors.Errorf("either json column or table alias must be specified for path prefix %v", prefix)
+	}
+
+	ro.paths[prefix] = pp
+	return nil
+}
+
+// RemovePathProps removes the properties of the specified path prefix from the
+// rendering options.
+func (ro *RenderingOptions) RemovePathProps(prefix string) *PathProps {
+	pp := ro.paths[prefix]
+	if pp != nil {
+		delete(ro.paths, prefix)
+	}
+
+	return pp
+}
+
+// GetPathProps retrieves the properties of the specified path prefix from the
+// rendering options.
+func (ro *RenderingOptions) GetPathProps(prefix string) *PathProps {
+	return ro.paths[prefix]
+}
+
+// SetMacroRegistry sets the registry of the user-defined macros that can be
+// used in addition to the builtin ones. If mr is nil then only the builtin
+// macros can be used.
+func (ro *RenderingOptions) SetMacroRegistry(mr *MacroRegistry) {
+	ro.macros = mr
+}
+
+// GetMacroRegistry returns the registry of the user-defined macros.
+func (ro *RenderingOptions) GetMacroRegistry() *MacroRegistry {
+	return ro.macros
+}
+
+// SetClock sets the function that returns the current time, relative to which
+// calendar macros like #today are computed. If a clock is set, then #now is
+// also resolved client-side, otherwise time.Now is used for calendar macros
+// and #now is rendered as the current time of the target engine.
+func (ro *RenderingOptions) SetClock(clock func() time.Time) {
+	ro.clock = clock
+}
+
+// GetClock returns the function that returns the current time, or nil if no
+// clock is set.
+func (ro *RenderingOptions) GetClock() func() time.Time {
+	return ro.clock
+}
+
+// SetLocation sets the time zone in which calendar macros like #today are
+// computed, and in which date and datetime literals without a time zone
+// offset are interpreted. If loc is nil then UTC is used.
+func (ro *RenderingOptions) SetLocation(loc *time.Location) {
+	ro.location = loc
+}
+
+// GetLocation returns the time zone in which calendar macros are computed and
+// literals are interpreted.
+func (ro *RenderingOptions) GetLocation() *time.Location {
+	if ro.location == nil {
+		return time.UTC
+	}
+
+	return ro.location
+}
+
 // EnableNamedParams enables named parameters in rendered code.
 func (ro *RenderingOptions) EnableNamedParams() {
 	if !ro.namedParams.enabled {
 		ro.namedParams.enabled = true
-		ro.namedParams.values = make(map[string]string)
+		ro.namedParams.params = []NamedParam{}
 	}
 }
 
 // DisableNamedParams disables named parameters in rendered code.
 func (ro *RenderingOptions) DisableNamedParams() {
 	ro.namedParams.enabled = false
-	ro.namedParams.values = nil
+	ro.namedParams.params = nil
 }
 
 // NamedParamsEnabled returns a Boolean value indicating whether or not
@@ -180,14 +491,57 @@ func (ro *RenderingOptions) NamedParamsEnabled() bool {
 	return ro.namedParams.enabled
 }
 
-// GetNamedParamValues returns a map containing the values of the named
-// parameters present in rendered code.
-func (ro *RenderingOptions) GetNamedParamValues() (map[string]string, error) {
+// GetNamedParams returns the named parameters present in the code rendered by
+// the last call to CodeGenerator.Visit, in the order they appear. Since they
+// are shared by all the callers of the same CodeGenerator, prefer
+// CodeGenerator.Generate and RenderResult when generating code concurrently.
+func (ro *RenderingOptions) GetNamedParams() ([]NamedParam, error) {
 	if !ro.namedParams.enabled {
 		return nil, errors.New("named parameters not enabled")
 	}
 
-	return ro.namedParams.values, nil
+	return ro.namedParams.params, nil
+}
+
+// GetNamedParamValues returns a map containing the values of the named
+// parameters present in rendered code.
+func (ro *RenderingOptions) GetNamedParamValues() (map[string]interface{}, error) {
+	params, err := ro.GetNamedParams()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		m[p.Name] = p.Value
+	}
+
+	return m, nil
+}
+
+// Args returns the values of the named parameters present in rendered code, in
+// the order they appear. The result is meant to be passed to database/sql when
+// placeholders are positional, e.g. ? or $1.
+func (ro *RenderingOptions) Args() ([]interface{}, error) {
+	params, err := ro.GetNamedParams()
+	if err != nil {
+		return nil, err
+	}
+
+	return (&RenderResult{NamedParams: params}).Args(), nil
+}
+
+// NamedArgs returns the named parameters present in rendered code as
+// sql.NamedArg values, in the order they appear. The result is meant to be
+// passed to database/sql through ToArgs when placeholders are named, e.g. :P1
+// or @P1.
+func (ro *RenderingOptions) NamedArgs() ([]sql.NamedArg, error) {
+	params, err := ro.GetNamedParams()
+	if err != nil {
+		return nil, err
+	}
+
+	return (&RenderResult{NamedParams: params}).NamedArgs(), nil
 }
 
 // SetNamedParamsPrefix sets the string that is prepended to parameter names.
@@ -204,3 +558,209 @@ func (ro *RenderingOptions) SetNamedParamsPrefix(p string) {
 func (ro *RenderingOptions) GetNamedParamsPrefix() string {
 	return ro.namedParams.prefix
 }
+
+// SetPlaceholderStyle sets the way placeholders are rendered. Whatever the
+// style, named parameters are listed in the order their placeholders appear in
+// rendered code, so that positional placeholders like ? can be bound as is.
+func (ro *RenderingOptions) SetPlaceholderStyle(ps PlaceholderStyle) {
+	ro.namedParams.style = ps
+}
+
+// GetPlaceholderStyle gets the way placeholders are rendered.
+func (ro *RenderingOptions) GetPlaceholderStyle() PlaceholderStyle {
+	return ro.namedParams.style
+}
+
+// now returns the current time according to the clock of ro, expressed in the
+// time zone of ro. If ro is nil then the current time in UTC is returned.
+func (ro *RenderingOptions) now() time.Time {
+	if ro == nil {
+		return time.Now().UTC()
+	}
+
+	clock := ro.clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return clock().In(ro.GetLocation())
+}
+
+// namedParamName returns the name of the named parameter at position pos,
+// where the first position is 1.
+func (ro *RenderingOptions) namedParamName(pos int) string {
+	return fmt.Sprintf("%s%d", ro.namedParams.prefix, pos)
+}
+
+// setNamedParams replaces the named parameters present in rendered code with p
+// if named parameters are enabled.
+func (ro *RenderingOptions) setNamedParams(p []NamedParam) {
+	if ro.namedParams.enabled {
+		ro.namedParams.params = p
+	}
+}
+
+// fieldType returns the type of the values the specified field is compatible
+// with, i.e. identType if the field is untyped or has no properties. Fields with
+// a set of allowed values are always enumerations.
+func (ro *RenderingOptions) fieldType(fieldName string) termType {
+	if fp := ro.GetFieldProps(fieldName); fp != nil {
+		if fp.Array {
+			return arrayType
+		} else if len(fp.Values) > 0 {
+			return enumType
+		}
+		return fp.Type.termType()
+	}
+
+	return identType
+}
+
+// elementType returns the declared type of the elements of the array field
+// with the specified name, or identType if the field is untyped. It returns an
+// error if the field is declared but not as an array.
+func (ro *RenderingOptions) elementType(fieldName string) (termType, error) {
+	fp := ro.GetFieldProps(fieldName)
+	if fp == nil {
+		return identType, nil
+	} else if !fp.Array {
+		if t := ro.fieldType(fieldName); t != identType {
+			return undefType, errors.Errorf("field %s of type %s is not an array", fieldName, toTypeName(t))
+		}
+		return identType, nil
+	} else if len(fp.Values) > 0 {
+		return enumType, nil
+	}
+
+	return fp.Type.termType(), nil
+}
+
+// validateBoolField verifies whether or not the field with the specified name
+// can be tested as a Boolean value.
+func (ro *RenderingOptions) validateBoolField(fieldName string) error {
+	if t := ro.fieldType(fieldName); t != identType && t != boolType {
+		return errors.Errorf("field %s of type %s cannot be tested as bool", fieldName, toTypeName(t))
+	}
+
+	return nil
+}
+
+// validateOperators verifies that every predicate in g only references fields
+// that allow its operator.
+func (ro *RenderingOptions) validateOperators(g *Grammar) error {
+	return walkPredicates(g.Disjunction, func(op string, fields []string) error {
+		for _, f := range fields {
+			if !ro.operatorAllowed(f, op) {
+				return errors.Errorf("field %s does not allow operator %s", f, op)
+			}
+		}
+		return nil
+	})
+}
+
+// operatorAllowed returns a Boolean value indicating whether or not the
+// specified field can be used with op.
+func (ro *RenderingOptions) operatorAllowed(fieldName string, op string) bool {
+	fp := ro.GetFieldProps(fieldName)
+	if fp == nil || len(fp.Operators) == 0 {
+		return true
+	}
+
+	for _, o := range fp.Operators {
+		if strings.EqualFold(o, op) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEnumerated returns a Boolean value indicating whether or not the field with
+// the specified name, or its elements if it is an array, can only be compared
+// with a set of allowed values.
+func (ro *RenderingOptions) isEnumerated(fieldName string) bool {
+	fp := ro.GetFieldProps(fieldName)
+	return fp != nil && len(fp.Values) > 0
+}
+
+// nativeFieldValue returns the native representation of the value v of the
+// specified field, along with its type, or an error if v is not one of the
+// allowed values of the field.
+func (ro *RenderingOptions) nativeFieldValue(fieldName string, v string) (string, termType, error) {
+	fp := ro.GetFieldProps(fieldName)
+
+	nv, ok := fp.Values[v]
+	if !ok {
+		values := make([]string, 0, len(fp.Values))
+		for k := range fp.Values {
+			values = append(values, k)
+		}
+		sort.Strings(values)
+		return "", undefType, errors.Errorf("invalid value %s for field %s, expected one of %s", v, fieldName, strings.Join(values, ", "))
+	}
+
+	switch nv := nv.(type) {
+	case nil:
+		return v, stringType, nil
+	case string:
+		return nv, stringType, nil
+	case int:
+		return strconv.Itoa(nv), intType, nil
+	case int64:
+		return strconv.FormatInt(nv, 10), intType, nil
+	case float64:
+		return strconv.FormatFloat(nv, 'f', -1, 64), decimalType, nil
+	case bool:
+		return strconv.FormatBool(nv), boolType, nil
+	}
+
+	return "", undefType, errors.Errorf("native value of %s for field %s is of unsupported type %T", v, fieldName, nv)
+}
+
+// pathPropsOf returns the properties of the longest path prefix of the
+// specified path, along with the remaining segments of the path, or nil if no
+// prefix of the path has properties.
+func (ro *RenderingOptions) pathPropsOf(path string) (*PathProps, []string) {
+	segments := strings.Split(path, ".")
+
+	for i := len(segments) - 1; i > 0; i-- {
+		if pp := ro.paths[strings.Join(segments[:i], ".")]; pp != nil {
+			return pp, segments[i:]
+		}
+	}
+
+	return nil, nil
+}
+
+// nativeFieldExpr returns the SQL expression the specified field stands for,
+// or an empty string if the field is not computed, or an error if the field is
+// not filterable.
+func (ro *RenderingOptions) nativeFieldExpr(fieldName string) (string, error) {
+	if fp := ro.GetFieldProps(fieldName); fp != nil {
+		if !fp.Filterable {
+			return "", errors.Errorf("field %v is not filterable", fieldName)
+		}
+		return fp.NativeExpr, nil
+	}
+
+	return "", nil
+}
+
+// nativeFieldName returns the native name of the specified field, or an error
+// if the field is not filterable or is computed. Fields without properties are
+// returned as is.
+func (ro *RenderingOptions) nativeFieldName(fieldName string) (string, error) {
+	if fp := ro.GetFieldProps(fieldName); fp != nil {
+		if !fp.Filterable {
+			return "", errors.Errorf("field %v is not filterable", fieldName)
+		}
+		if len(fp.NativeExpr) > 0 {
+			return "", errors.Errorf("field %v is computed and can only be used in sql", fieldName)
+		}
+		if len(fp.NativeName) > 0 {
+			return fp.NativeName, nil
+		}
+	}
+
+	return fieldName, nil
+}
@@ -0,0 +1,56 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import "database/sql"
+
+// RenderResult is the outcome of a single code generation, i.e. the native
+// query along with the named parameters it contains.
+type RenderResult struct {
+	// Code is the native query.
+	Code string
+
+	// NamedParams contains the named parameters present in Code, in the order
+	// they appear. It is empty if named parameters are not enabled.
+	NamedParams []NamedParam
+}
+
+// Args returns the values of the named parameters in rr, in the order they
+// appear. The result is meant to be passed to database/sql when placeholders
+// are positional, e.g. ? or $1.
+func (rr *RenderResult) Args() []interface{} {
+	args := make([]interface{}, len(rr.NamedParams))
+	for i, p := range rr.NamedParams {
+		args[i] = p.Value
+	}
+
+	return args
+}
+
+// NamedArgs returns the named parameters in rr as sql.NamedArg values, in the
+// order they appear. The result is meant to be passed to database/sql through
+// ToArgs when placeholders are named, e.g. :P1 or @P1.
+func (rr *RenderResult) NamedArgs() []sql.NamedArg {
+	args := make([]sql.NamedArg, len(rr.NamedParams))
+	for i, p := range rr.NamedParams {
+		args[i] = sql.Named(p.Name, p.Value)
+	}
+
+	return args
+}
+
+// ToArgs converts namedArgs into a slice that can be spread into the variadic
+// arguments of database/sql, e.g. db.QueryContext(ctx, rr.Code,
+// ToArgs(rr.NamedArgs())...).
+func ToArgs(namedArgs []sql.NamedArg) []interface{} {
+	args := make([]interface{}, len(namedArgs))
+	for i, a := range namedArgs {
+		args[i] = a
+	}
+
+	return args
+}
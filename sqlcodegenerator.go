@@ -8,41 +8,49 @@ package espressopp
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	duration "github.com/channelmeter/iso8601duration"
-	pluralize "github.com/gertd/go-pluralize"
 	"github.com/pkg/errors"
 )
 
-type termType int
-
-const (
-	undefType termType = iota
-	identType
-	intType
-	decimalType
-	stringType
-	dateType
-	timeType
-	dateTimeType
-	boolType
-)
-
 // SqlCodeGenerator is the CodeGenerator implementation that produces native SQL
 // from Espresso++ expressions.
 type SqlCodeGenerator struct {
 	// RenderingOptions is used to control the way native SQL is produced.
 	RenderingOptions *RenderingOptions
+
+	// Dialect is used to render those constructs that are not supported
+	// uniformly across database engines.
+	Dialect Dialect
+
+	// pending contains the values bound by the current call to Generate, which
+	// become named parameters only once their placeholders are in place.
+	pending []interface{}
+
+	// marker is the random token that identifies the markers produced by the
+	// current call to Generate, so that literals cannot forge them.
+	marker string
 }
 
-// NewSqlCodeGenerator creates a new instance of SqlCodeGenerator.
+// NewSqlCodeGenerator creates a new instance of SqlCodeGenerator that produces
+// generic SQL.
 func NewSqlCodeGenerator() *SqlCodeGenerator {
+	return NewSqlCodeGeneratorWithDialect(NewGenericDialect())
+}
+
+// NewSqlCodeGeneratorWithDialect creates a new instance of SqlCodeGenerator that
+// produces SQL in the specified dialect.
+func NewSqlCodeGeneratorWithDialect(d Dialect) *SqlCodeGenerator {
 	return &SqlCodeGenerator{
 		RenderingOptions: NewRenderingOptions(),
+		Dialect:          d,
 	}
 }
 
@@ -61,29 +69,83 @@ func (cg *SqlCodeGenerator) Visit(i Interpreter, r io.Reader, w io.Writer) error
 		return errors.Wrapf(err, "error parsing %v", buf.String())
 	}
 
-	s, err := cg.emitGrammar(grammar)
+	rr, err := cg.Generate(grammar)
 	if err != nil {
-		return errors.Wrapf(err, "error generating sql")
+		return err
+	}
+
+	if cg.RenderingOptions != nil {
+		cg.RenderingOptions.setNamedParams(rr.NamedParams)
 	}
 
-	_, err = io.WriteString(w, s)
+	_, err = io.WriteString(w, rr.Code)
 	return err
 }
 
+// Generate produces native SQL from g. It is safe for concurrent use since the
+// state of each call is held by a copy of cg and the named parameters are
+// returned along with the query instead of being stored in RenderingOptions.
+func (cg *SqlCodeGenerator) Generate(g *Grammar) (*RenderResult, error) {
+	if cg.Dialect == nil {
+		return nil, errors.New("dialect not specified")
+	}
+
+	c := *cg
+	c.pending = nil
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrapf(err, "error generating sql")
+	}
+	c.marker = hex.EncodeToString(b)
+
+	if c.RenderingOptions != nil {
+		if err := c.RenderingOptions.validateOperators(g); err != nil {
+			return nil, errors.Wrapf(err, "error generating sql")
+		}
+	}
+
+	s, err := c.emitGrammar(g)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error generating sql")
+	}
+
+	return c.resolvePlaceholders(s), nil
+}
+
 // emitGrammar renders g.
 func (cg *SqlCodeGenerator) emitGrammar(g *Grammar) (string, error) {
-	var err error
-	var sb strings.Builder
+	return cg.emitDisjunction(g.Disjunction)
+}
+
+// emitDisjunction renders d.
+func (cg *SqlCodeGenerator) emitDisjunction(d *Disjunction) (string, error) {
+	conjunctions := make([]string, len(d.Conjunctions))
 
-	for _, e := range g.Expressions {
+	for i, c := range d.Conjunctions {
+		s, err := cg.emitConjunction(c)
+		if err != nil {
+			return "", err
+		}
+		conjunctions[i] = s
+	}
+
+	return strings.Join(conjunctions, " OR "), nil
+}
+
+// emitConjunction renders c.
+func (cg *SqlCodeGenerator) emitConjunction(c *Conjunction) (string, error) {
+	expressions := make([]string, len(c.Expressions))
+
+	for i, e := range c.Expressions {
 		s, err := cg.emitExpression(e)
 		if err != nil {
 			return "", err
 		}
-		sb.WriteString(s)
+		expressions[i] = s
 	}
 
-	return sb.String(), err
+	return strings.Join(expressions, " AND "), nil
 }
 
 // emitExpression renders e.
@@ -91,9 +153,7 @@ func (cg *SqlCodeGenerator) emitExpression(e *Expression) (string, error) {
 	var err error
 	var s string
 
-	if e.Op != nil {
-		s = fmt.Sprintf(" %s ", strings.ToUpper(*e.Op))
-	} else if e.SubExpression != nil {
+	if e.SubExpression != nil {
 		s, err = cg.emitSubExpression(e.SubExpression)
 	} else if e.Comparison != nil {
 		s, err = cg.emitComparison(e.Comparison)
@@ -103,6 +163,12 @@ func (cg *SqlCodeGenerator) emitExpression(e *Expression) (string, error) {
 		s, err = cg.emitRange(e.Range)
 	} else if e.Match != nil {
 		s, err = cg.emitMatch(e.Match)
+	} else if e.In != nil {
+		s, err = cg.emitIn(e.In)
+	} else if e.Has != nil {
+		s, err = cg.emitHas(e.Has)
+	} else if e.Quantified != nil {
+		s, err = cg.emitQuantified(e.Quantified)
 	} else if e.Is != nil {
 		s, err = cg.emitIs(e.Is)
 	}
@@ -112,26 +178,16 @@ func (cg *SqlCodeGenerator) emitExpression(e *Expression) (string, error) {
 
 // emitExpression renders se.
 func (cg *SqlCodeGenerator) emitSubExpression(se *SubExpression) (string, error) {
-	var err error
-	var sb strings.Builder
-
-	if se.Not {
-		sb.WriteString("NOT ")
+	s, err := cg.emitDisjunction(se.Disjunction)
+	if err != nil {
+		return "", err
 	}
 
-	sb.WriteString("(")
-
-	for _, e := range se.Expressions {
-		s, err := cg.emitExpression(e)
-		if err != nil {
-			return "", err
-		}
-		sb.WriteString(s)
+	if se.Not {
+		return fmt.Sprintf("NOT (%s)", s), nil
 	}
 
-	sb.WriteString(")")
-
-	return sb.String(), err
+	return fmt.Sprintf("(%s)", s), nil
 }
 
 // emitComparison renders c.
@@ -146,11 +202,11 @@ func (cg *SqlCodeGenerator) emitComparison(c *Comparison) (string, error) {
 		return "", err
 	}
 
-	tt, err := cg.validateTypes(tt1, tt2)
+	tt, err := validateTypes(tt1, tt2)
 	if err != nil {
 		return "", err
-	} else if tt != intType && tt != decimalType && tt != dateType && tt != timeType && tt != dateTimeType {
-		return "", errors.Errorf("cannot compare values of type %s", cg.toTypeName(tt))
+	} else if !isOrdered(tt) {
+		return "", errors.Errorf("cannot compare values of type %s", toTypeName(tt))
 	}
 
 	var op string
@@ -170,17 +226,17 @@ func (cg *SqlCodeGenerator) emitComparison(c *Comparison) (string, error) {
 
 // emitEquality renders e.
 func (cg *SqlCodeGenerator) emitEquality(e *Equality) (string, error) {
-	t1, tt1, err := cg.emitTermOrMath(e.TermOrMath1)
+	t1, tt1, err := cg.emitOperand(e.TermOrMath1, e.TermOrMath2)
 	if err != nil {
 		return "", err
 	}
 
-	t2, tt2, err := cg.emitTermOrMath(e.TermOrMath2)
+	t2, tt2, err := cg.emitOperand(e.TermOrMath2, e.TermOrMath1)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = cg.validateTypes(tt1, tt2)
+	_, err = validateTypes(tt1, tt2)
 	if err != nil {
 		return "", err
 	}
@@ -213,15 +269,15 @@ func (cg *SqlCodeGenerator) emitRange(r *Range) (string, error) {
 		return "", err
 	}
 
-	tt, err := cg.validateTypes(tt1, tt2)
+	tt, err := validateTypes(tt1, tt2)
 	if err != nil {
 		return "", err
 	}
-	tt, err = cg.validateTypes(tt, tt3)
+	tt, err = validateTypes(tt, tt3)
 	if err != nil {
 		return "", err
-	} else if tt != intType && tt != decimalType {
-		return "", errors.Errorf("cannot range values of type %s", cg.toTypeName(tt))
+	} else if !isOrdered(tt) {
+		return "", errors.Errorf("cannot range values of type %s", toTypeName(tt))
 	}
 
 	return fmt.Sprintf("%s %s %s %s %s", t1,
@@ -229,91 +285,319 @@ func (cg *SqlCodeGenerator) emitRange(r *Range) (string, error) {
 		strings.ToUpper(r.And), t3), err
 }
 
-// emitMatch renders m.
+// emitMatch renders m. LIKE wildcards in the pattern are escaped so that they
+// match literally.
 func (cg *SqlCodeGenerator) emitMatch(m *Match) (string, error) {
 	t1, tt1, err := cg.emitTerm(m.Term1)
 	if err != nil {
 		return "", err
 	}
 
-	t2, tt2, err := cg.emitTerm(m.Term2)
+	tt, err := validateTypes(tt1, termTypeOf(m.Term2))
 	if err != nil {
 		return "", err
+	} else if tt != stringType {
+		return "", errors.Errorf("cannot match values of type %s", toTypeName(tt))
+	} else if m.Term2.String == nil {
+		return "", errors.Errorf("%s requires a string pattern", m.Op)
 	}
 
-	tt, err := cg.validateTypes(tt1, tt2)
-	if err != nil {
-		return "", err
-	} else if tt != stringType {
-		return "", errors.Errorf("cannot match values of type %s", cg.toTypeName(tt))
+	op, ci := matchOp(m.Op)
+
+	if op == "matches" {
+		return cg.Dialect.Regexp(t1, *m.Term2.String, cg.binder())
 	}
 
-	t2 = strings.ReplaceAll(strings.ReplaceAll(t2, "'", ""), "\"", "")
+	if op == "eq" {
+		t2, err := cg.applyRenderingOptions(*m.Term2.String, stringType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("LOWER(%s) = LOWER(%s)", t1, t2), nil
+	}
 
-	switch m.Op {
+	p := cg.Dialect.EscapeLike(*m.Term2.String)
+
+	switch op {
 	case "startswith":
-		t2 = fmt.Sprintf("'%s%%'", t2)
+		p = p + "%"
 	case "endswith":
-		t2 = fmt.Sprintf("'%%%s'", t2)
+		p = "%" + p
 	case "contains":
-		t2 = fmt.Sprintf("'%%%s%%'", t2)
+		p = "%" + p + "%"
+	}
+
+	t2, err := cg.applyRenderingOptions(p, stringType)
+	if err != nil {
+		return "", err
+	}
+
+	if ci {
+		return cg.Dialect.ILike(t1, t2), nil
+	}
+
+	return cg.Dialect.Like(t1, t2), nil
+}
+
+// emitIn renders i. Each term in the list is bound to its own named parameter
+// if named parameters are enabled.
+func (cg *SqlCodeGenerator) emitIn(i *In) (string, error) {
+	s, tt, err := cg.emitTermOrMath(i.TermOrMath)
+	if err != nil {
+		return "", err
+	}
+
+	terms := make([]string, len(i.Terms))
+	types := make([]termType, len(i.Terms))
+	f := cg.enumFieldOf(i.TermOrMath)
+
+	for j, t := range i.Terms {
+		if len(f) > 0 && t.String != nil {
+			terms[j], types[j], err = cg.emitEnumValue(f, *t.String)
+		} else {
+			terms[j], types[j], err = cg.emitTerm(t)
+		}
+		if err != nil {
+			return "", err
+		}
+		if tt, err = validateTypes(tt, types[j]); err != nil {
+			return "", err
+		}
+	}
+
+	s = cg.toTypedLiteral(s, tt)
+	for j := range terms {
+		terms[j] = cg.toTypedLiteral(terms[j], types[j])
+	}
+
+	op := "IN"
+	if i.Not {
+		op = "NOT IN"
+	}
+
+	return fmt.Sprintf("%s %s (%s)", s, op, strings.Join(terms, ", ")), nil
+}
+
+// emitHas renders h.
+func (cg *SqlCodeGenerator) emitHas(h *Has) (string, error) {
+	return cg.emitArrayPredicate(h.Field, false, "has", h.Term)
+}
+
+// emitQuantified renders q.
+func (cg *SqlCodeGenerator) emitQuantified(q *Quantified) (string, error) {
+	return cg.emitArrayPredicate(q.Field, q.Quantifier == "all", q.op(), q.terms()...)
+}
+
+// emitArrayPredicate renders the test of whether any element of the array field
+// f, or every element if all is true, satisfies op with the terms in ts. Terms
+// are type-checked against the declared type of the elements.
+func (cg *SqlCodeGenerator) emitArrayPredicate(f string, all bool, op string, ts ...*Term) (string, error) {
+	a, err := cg.applyRenderingOptions(f, identType)
+	if err != nil {
+		return "", err
+	}
+
+	et := identType
+	if cg.RenderingOptions != nil {
+		if et, err = cg.RenderingOptions.elementType(f); err != nil {
+			return "", err
+		}
 	}
 
-	return fmt.Sprintf("%s %s %s", t1, "LIKE", t2), err
+	terms := make([]string, len(ts))
+
+	for i, t := range ts {
+		var tt termType
+		if et == enumType && t.String != nil {
+			terms[i], tt, err = cg.emitEnumValue(f, *t.String)
+		} else {
+			terms[i], tt, err = cg.emitTerm(t)
+		}
+		if err != nil {
+			return "", err
+		}
+		if vt, err := validateTypes(et, tt); err != nil {
+			return "", err
+		} else if (op == "gt" || op == "gte" || op == "lt" || op == "lte") && !isOrdered(vt) {
+			return "", errors.Errorf("cannot compare values of type %s", toTypeName(vt))
+		}
+		terms[i] = cg.toTypedLiteral(terms[i], tt)
+	}
+
+	return cg.Dialect.ArrayPredicate(a, all, op, terms), nil
 }
 
 // emitIs renders i.
 func (cg *SqlCodeGenerator) emitIs(i *Is) (string, error) {
-	var sb strings.Builder
+	var err error
+	var s string
 
 	if i.IsWithExplicitValue != nil {
-		sb.WriteString(i.IsWithExplicitValue.Ident)
+		if s, err = cg.applyRenderingOptions(i.IsWithExplicitValue.Ident, identType); err != nil {
+			return "", err
+		}
 		if i.IsWithExplicitValue.Value == "null" {
 			var not string
 			if i.IsWithExplicitValue.Not {
 				not = "NOT "
 			}
-			sb.WriteString(fmt.Sprintf(" IS %s%s", not, strings.ToUpper(i.IsWithExplicitValue.Value)))
+			s = fmt.Sprintf("%s IS %s%s", s, not, strings.ToUpper(i.IsWithExplicitValue.Value))
 		} else {
+			if err = cg.validateBoolField(i.IsWithExplicitValue.Ident); err != nil {
+				return "", err
+			}
 			var not string
 			if i.IsWithExplicitValue.Not {
 				not = "!"
 			}
-			boolean := "0"
-			if i.IsWithExplicitValue.Value == "true" {
-				boolean = "1"
-			}
-			sb.WriteString(fmt.Sprintf(" %s= %s", not, boolean))
+			boolean := cg.Dialect.Bool(i.IsWithExplicitValue.Value == "true")
+			s = fmt.Sprintf("%s %s= %s", s, not, boolean)
 		}
 	} else if i.IsWithImplicitValue != nil {
-		sb.WriteString(i.IsWithImplicitValue.Ident)
-		boolean := "1"
-		if i.IsWithImplicitValue.Not {
-			boolean = "0"
+		if s, err = cg.applyRenderingOptions(i.IsWithImplicitValue.Ident, identType); err != nil {
+			return "", err
+		}
+		if err = cg.validateBoolField(i.IsWithImplicitValue.Ident); err != nil {
+			return "", err
+		}
+		s = fmt.Sprintf("%s = %s", s, cg.Dialect.Bool(!i.IsWithImplicitValue.Not))
+	}
+
+	return s, err
+}
+
+// emitOperand renders tm, which is compared with other. String literals that
+// are compared with enumerations are rendered as their native values.
+func (cg *SqlCodeGenerator) emitOperand(tm *TermOrMath, other *TermOrMath) (string, termType, error) {
+	if f := cg.enumFieldOf(other); len(f) > 0 {
+		if t := termOf(tm); t != nil && t.String != nil {
+			return cg.emitEnumValue(f, *t.String)
 		}
-		sb.WriteString(fmt.Sprintf(" = %s", boolean))
 	}
 
-	return sb.String(), nil
+	return cg.emitTermOrMath(tm)
 }
 
-// emitTermOrMath renders tm.
+// enumFieldOf returns the name of the field tm consists of if the field is an
+// enumeration with a set of allowed values, or an empty string otherwise.
+func (cg *SqlCodeGenerator) enumFieldOf(tm *TermOrMath) string {
+	if cg.RenderingOptions == nil || !isField(tm) {
+		return ""
+	}
+
+	f := *termOf(tm).Identifier
+	if fp := cg.RenderingOptions.GetFieldProps(f); fp == nil || len(fp.Values) == 0 {
+		return ""
+	}
+
+	return f
+}
+
+// emitEnumValue renders the native representation of the value v of the
+// enumeration f.
+func (cg *SqlCodeGenerator) emitEnumValue(f string, v string) (string, termType, error) {
+	nv, t, err := cg.RenderingOptions.nativeFieldValue(f, v)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	s, err := cg.applyRenderingOptions(nv, t)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	return s, enumType, nil
+}
+
+// emitTermOrMath renders tm. Durations added to or subtracted from dates are
+// rendered as date arithmetic as required by the dialect.
 func (cg *SqlCodeGenerator) emitTermOrMath(tm *TermOrMath) (string, termType, error) {
 	var err error
 	var s string
 	var t termType
 
-	if tm.Math != nil {
-		s, t, err = cg.emitMath(tm.Math)
-	} else if tm.SubMath != nil {
-		if s, t, err = cg.emitMath(tm.SubMath); err == nil {
+	products := tm.Products
+
+	if d := durationOf(tm.Product); d != nil && len(products) > 0 && products[0].Op == "add" && durationOf(products[0].Product) == nil {
+		if s, t, err = cg.emitProduct(products[0].Product); err != nil {
+			return "", undefType, err
+		}
+		if s, t, err = cg.emitDateAdd(s, t, d, false); err != nil {
+			return "", undefType, err
+		}
+		products = products[1:]
+	} else if s, t, err = cg.emitProduct(tm.Product); err != nil {
+		return "", undefType, err
+	}
+
+	for _, as := range products {
+		if d := durationOf(as.Product); d != nil {
+			if s, t, err = cg.emitDateAdd(s, t, d, as.Op == "sub"); err != nil {
+				return "", undefType, err
+			}
+			continue
+		}
+
+		s2, t2, err := cg.emitProduct(as.Product)
+		if err != nil {
+			return "", undefType, err
+		}
+		if s, t, err = cg.emitArithmetic(s, t, as.Op, s2, t2); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	return s, t, nil
+}
+
+// emitProduct renders p.
+func (cg *SqlCodeGenerator) emitProduct(p *Product) (string, termType, error) {
+	s, t, err := cg.emitFactor(p.Factor)
+	if err != nil {
+		return "", undefType, err
+	}
+
+	for _, md := range p.Factors {
+		s2, t2, err := cg.emitFactor(md.Factor)
+		if err != nil {
+			return "", undefType, err
+		}
+		if s, t, err = cg.emitArithmetic(s, t, md.Op, s2, t2); err != nil {
+			return "", undefType, err
+		}
+	}
+
+	return s, t, nil
+}
+
+// emitFactor renders f.
+func (cg *SqlCodeGenerator) emitFactor(f *Factor) (string, termType, error) {
+	var err error
+	var s string
+	var t termType
+
+	if f.SubMath != nil {
+		if s, t, err = cg.emitTermOrMath(f.SubMath); err == nil {
 			s = fmt.Sprintf("(%s)", s)
 		}
-	} else if tm.Term != nil {
-		s, t, err = cg.emitTerm(tm.Term)
+	} else if f.Term != nil {
+		s, t, err = cg.emitTerm(f.Term)
 	}
 
-	return s, t, err
+	if err != nil || !f.Minus {
+		return s, t, err
+	}
+
+	if t != identType && t != intType && t != decimalType {
+		return "", undefType, errors.Errorf("cannot negate values of type %s", toTypeName(t))
+	}
+
+	// prevent -- from starting a comment when negating negative literals
+	if strings.HasPrefix(s, "-") {
+		return fmt.Sprintf("-(%s)", s), t, nil
+	}
+
+	return "-" + s, t, nil
 }
 
 // emitTerm renders t.
@@ -322,9 +606,11 @@ func (cg *SqlCodeGenerator) emitTerm(t *Term) (string, termType, error) {
 	var s string
 	var tt termType
 
-	if t.Identifier != nil {
-		tt = identType
-		s, err = cg.applyRenderingOptions(*t.Identifier, tt)
+	if t.Function != nil {
+		s, tt, err = cg.emitFunction(t.Function)
+	} else if t.Identifier != nil {
+		s, err = cg.applyRenderingOptions(*t.Identifier, identType)
+		tt = cg.fieldType(*t.Identifier)
 	} else if t.Integer != nil {
 		tt = intType
 		s, err = cg.applyRenderingOptions(strconv.Itoa(*t.Integer), tt)
@@ -333,24 +619,19 @@ func (cg *SqlCodeGenerator) emitTerm(t *Term) (string, termType, error) {
 		s, err = cg.applyRenderingOptions(strconv.FormatFloat(*t.Decimal, 'f', -1, 64), tt)
 	} else if t.String != nil {
 		tt = stringType
-		s, err = cg.applyRenderingOptions(fmt.Sprintf("'%s'", *t.String), tt)
+		s, err = cg.applyRenderingOptions(*t.String, tt)
 	} else if t.Date != nil {
 		tt = dateType
-		s, err = cg.applyRenderingOptions(fmt.Sprintf("'%s'", *t.Date), tt)
+		s, err = cg.applyRenderingOptions(*t.Date, tt)
 	} else if t.Time != nil {
 		tt = timeType
-		s, err = cg.applyRenderingOptions(fmt.Sprintf("'%s'", *t.Time), tt)
+		s, err = cg.applyRenderingOptions(*t.Time, tt)
 	} else if t.DateTime != nil {
 		tt = dateTimeType
-		s, err = cg.applyRenderingOptions(fmt.Sprintf("'%s'", strings.Replace(*t.DateTime, "T", " ", -1)), tt)
+		s, err = cg.applyRenderingOptions(strings.Replace(*t.DateTime, "T", " ", -1), tt)
 	} else if t.Bool != nil {
 		tt = boolType
-		if *t.Bool == "true" {
-			s = "1"
-		} else {
-			s = "0"
-		}
-		s, err = cg.applyRenderingOptions(s, tt)
+		s, err = cg.applyRenderingOptions(*t.Bool, tt)
 	} else if t.Macro != nil {
 		s, tt, err = cg.emitMacro(t.Macro)
 	}
@@ -358,46 +639,46 @@ func (cg *SqlCodeGenerator) emitTerm(t *Term) (string, termType, error) {
 	return s, tt, err
 }
 
-// emitMath renders m.
-func (cg *SqlCodeGenerator) emitMath(m *Math) (string, termType, error) {
-	t1, tt1, err := cg.emitTerm(m.Term1)
+// emitFunction renders f after verifying that its arguments match its signature.
+func (cg *SqlCodeGenerator) emitFunction(f *Function) (string, termType, error) {
+	args := make([]string, len(f.Args))
+	types := make([]termType, len(f.Args))
+
+	for i, a := range f.Args {
+		var err error
+		if args[i], types[i], err = cg.emitTermOrMath(a); err != nil {
+			return "", undefType, err
+		}
+		args[i] = cg.toTypedLiteral(args[i], types[i])
+	}
+
+	t, err := validateCall(f.Name, types)
 	if err != nil {
 		return "", undefType, err
 	}
 
-	t2, tt2, err := cg.emitTerm(m.Term2)
+	s, err := cg.Dialect.Function(f.Name, args)
 	if err != nil {
 		return "", undefType, err
 	}
 
-	tt, err := cg.validateTypes(tt1, tt2)
+	return s, t, nil
+}
+
+// emitArithmetic renders the arithmetic operation s1 op s2, where s1 and s2
+// are of type t1 and t2 respectively.
+func (cg *SqlCodeGenerator) emitArithmetic(s1 string, t1 termType, op string, s2 string, t2 termType) (string, termType, error) {
+	t, err := validateTypes(t1, t2)
 	if err != nil {
 		return "", undefType, err
-	} else if tt != intType && tt != decimalType && tt != dateType && tt != timeType && tt != dateTimeType {
-		return "", tt, errors.Errorf("cannot compute values of type %s", cg.toTypeName(tt))
-	}
-
-	if tt == dateType || tt == timeType || tt == dateTimeType {
-		var f string
-		switch tt {
-		case dateType:
-			f = "DATE"
-		case timeType:
-			f = "TIME"
-		case dateTimeType:
-			f = "TIMESTAMP"
-		}
-
-		if strings.HasPrefix(t1, "'") && strings.HasSuffix(t1, "'") {
-			t1 = fmt.Sprintf("%s %s", f, t1)
-		}
-		if strings.HasPrefix(t2, "'") && strings.HasSuffix(t2, "'") {
-			t2 = fmt.Sprintf("%s %s", f, t2)
-		}
+	} else if t != identType && t != intType && t != decimalType && t != dateType && t != timeType && t != dateTimeType {
+		return "", t, errors.Errorf("cannot compute values of type %s", toTypeName(t))
 	}
 
-	var op string
-	switch m.Op {
+	s1 = cg.toTypedLiteral(s1, t)
+	s2 = cg.toTypedLiteral(s2, t)
+
+	switch op {
 	case "add":
 		op = "+"
 	case "sub":
@@ -408,7 +689,7 @@ func (cg *SqlCodeGenerator) emitMath(m *Math) (string, termType, error) {
 		op = "/"
 	}
 
-	return fmt.Sprintf("%s %s %s", t1, op, t2), tt, err
+	return fmt.Sprintf("%s %s %s", s1, op, s2), t, nil
 }
 
 // emitMacro renders m.
@@ -422,131 +703,253 @@ func (cg *SqlCodeGenerator) emitMacro(m *Macro) (string, termType, error) {
 		s, t, err = cg.emitNowMacro(m)
 	case "#duration":
 		s, t, err = cg.emitDurationMacro(m)
+	case "#today", "#startof", "#endof", "#ago":
+		s, t, err = cg.emitCalendarMacro(m)
+	default:
+		s, t, err = cg.emitUserMacro(m)
 	}
 
 	return s, t, err
 }
 
-// emitNowMacro renders m.
+// emitUserMacro renders the user-defined macro m with the renderer for the
+// dialect, if any, or with the one for sql.
+func (cg *SqlCodeGenerator) emitUserMacro(m *Macro) (string, termType, error) {
+	var mr *MacroRegistry
+	if cg.RenderingOptions != nil {
+		mr = cg.RenderingOptions.GetMacroRegistry()
+	}
+
+	e, t, err := mr.expand(m, cg.Dialect.Name(), "sql")
+	if err != nil {
+		return "", undefType, err
+	} else if nc, ok := e.(NativeCode); ok {
+		return string(nc), t, nil
+	}
+
+	s, _, err := cg.emitTerm(e.(*Term))
+	return s, t, err
+}
+
+// emitCalendarMacro renders m as the literal it expands to relative to the
+// clock and time zone of the rendering options.
+func (cg *SqlCodeGenerator) emitCalendarMacro(m *Macro) (string, termType, error) {
+	t, tt, err := expandCalendarMacro(m, cg.RenderingOptions.now())
+	if err != nil {
+		return "", undefType, err
+	}
+
+	s, _, err := cg.emitTerm(t)
+	return s, tt, err
+}
+
+// emitNowMacro renders m as the current time according to the clock of the
+// rendering options, if any, or as the current time of the database otherwise.
 func (cg *SqlCodeGenerator) emitNowMacro(m *Macro) (string, termType, error) {
+	if err := expectNoArgs(m); err != nil {
+		return "", undefType, err
+	} else if cg.RenderingOptions != nil && cg.RenderingOptions.GetClock() != nil {
+		return cg.emitCalendarMacro(m)
+	}
+
 	return "CURRENT_TIMESTAMP", dateTimeType, nil
 }
 
 // emitDurationMacro renders m.
 func (cg *SqlCodeGenerator) emitDurationMacro(m *Macro) (string, termType, error) {
-	if m.Args == nil {
-		return "", undefType, errors.Errorf("%s: missing parameter: iso8601 interval", m.Name)
-	}
-
-	const interval = "INTERVAL '%s'"
-	var err error
-	var items []string
-	p := pluralize.NewClient()
-
-	for _, a := range m.Args {
-		s, t, err := cg.emitTerm(a)
-		if err != nil {
-			return "", undefType, err
-		} else if t != stringType {
-			return "", undefType, errors.Errorf("iso8601 interval cannot be of type %s", cg.toTypeName(t))
-		}
-		d, err := duration.FromString(s)
-		if err != nil {
-			return "", undefType, err
-		}
-		if d.Years > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("YEAR", d.Years, true)))
-		}
-		if d.Weeks > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("WEEK", d.Weeks, true)))
-		}
-		if d.Days > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("DAY", d.Days, true)))
-		}
-		if d.Hours > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("HOUR", d.Hours, true)))
-		}
-		if d.Minutes > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("MINUTE", d.Minutes, true)))
-		}
-		if d.Seconds > 0 {
-			items = append(items, fmt.Sprintf(interval, p.Pluralize("SECOND", d.Seconds, true)))
-		}
+	d, err := toDuration(m)
+	if err != nil {
+		return "", undefType, err
 	}
 
-	var sb strings.Builder
-	for i, s := range items {
-		sb.WriteString(s)
-		if i > 0 {
-			sb.WriteString(" ")
-		}
+	s, err := cg.Dialect.Interval(d, cg.binder())
+	if err != nil {
+		return "", undefType, err
 	}
 
-	return sb.String(), dateTimeType, err
+	return s, dateTimeType, nil
 }
 
-// validateTypes verifies whether or not t1 and t2 are compatible, and if they are,
-// it returns the result type of the current expression.
-func (cg *SqlCodeGenerator) validateTypes(t1 termType, t2 termType) (termType, error) {
-	var err error
-	var t termType
+// emitDateAdd renders the addition of the duration m to s, which is of type t.
+// If sub is true, then the duration is subtracted instead.
+func (cg *SqlCodeGenerator) emitDateAdd(s string, t termType, m *Macro, sub bool) (string, termType, error) {
+	if t != identType && t != dateType && t != timeType && t != dateTimeType {
+		return "", undefType, errors.Errorf("cannot add an interval to values of type %s", toTypeName(t))
+	}
+
+	d, err := toDuration(m)
+	if err != nil {
+		return "", undefType, err
+	}
 
-	if t1 == t2 {
-		t = t1
-	} else if t1 == identType {
-		t = t2
-	} else if t2 == identType {
-		t = t1
-	} else {
-		err = errors.Errorf("type %s is not compatible with type %s", cg.toTypeName(t1), cg.toTypeName(t2))
+	if t == identType {
+		t = dateTimeType
 	}
 
-	return t, err
+	s, err = cg.Dialect.DateAdd(cg.toTypedLiteral(s, t), d, sub, cg.binder())
+	return s, t, err
 }
 
-// toTypeName returns the name of t.
-func (cg *SqlCodeGenerator) toTypeName(t termType) string {
-	var n string
+// toTypedLiteral renders s as a typed literal if it is a quoted literal of type
+// t, otherwise it returns s as is.
+func (cg *SqlCodeGenerator) toTypedLiteral(s string, t termType) string {
+	if !strings.HasPrefix(s, "'") || !strings.HasSuffix(s, "'") {
+		return s
+	}
 
 	switch t {
-	case stringType:
-		n = "string"
-	case intType:
-		n = "int"
-	case decimalType:
-		n = "decimal"
 	case dateType:
-		n = "date"
+		s = cg.Dialect.DateLiteral(s)
 	case timeType:
-		n = "time"
+		s = cg.Dialect.TimeLiteral(s)
 	case dateTimeType:
-		n = "datetime"
-	case boolType:
-		n = "bool"
+		s = cg.Dialect.DateTimeLiteral(s)
 	}
 
-	return n
+	return s
 }
 
-// applyRenderingOptions applies the rendering options to f.
+// fieldType returns the declared type of the field f, or identType if f is
+// untyped.
+func (cg *SqlCodeGenerator) fieldType(f string) termType {
+	if cg.RenderingOptions == nil {
+		return identType
+	}
+
+	return cg.RenderingOptions.fieldType(f)
+}
+
+// validateBoolField verifies whether or not the field f can be tested as a
+// Boolean value.
+func (cg *SqlCodeGenerator) validateBoolField(f string) error {
+	if cg.RenderingOptions == nil {
+		return nil
+	}
+
+	return cg.RenderingOptions.validateBoolField(f)
+}
+
+// applyRenderingOptions applies the rendering options to f, which is either a
+// field name or the raw value of a literal of type t. Computed fields are
+// replaced with their expression, paths into JSON columns or joined tables are
+// resolved, whereas literals are either replaced with named parameters or
+// escaped according to the dialect.
 func (cg *SqlCodeGenerator) applyRenderingOptions(f string, t termType) (string, error) {
-	if cg.RenderingOptions != nil {
-		if t == identType {
-			if val := cg.RenderingOptions.GetFieldProps(f); val != nil {
-				if !val.Filterable {
-					return "", errors.Errorf("field %v is not filterable", f)
-				}
-				if len(val.NativeName) > 0 {
-					return val.NativeName, nil
+	if t == identType {
+		n := f
+		if cg.RenderingOptions != nil {
+			e, err := cg.RenderingOptions.nativeFieldExpr(f)
+			if err != nil {
+				return "", err
+			} else if len(e) > 0 {
+				return "(" + e + ")", nil
+			}
+			if n, err = cg.RenderingOptions.nativeFieldName(f); err != nil {
+				return "", err
+			}
+			if pp, keys := cg.RenderingOptions.pathPropsOf(n); pp != nil {
+				if len(pp.TableAlias) > 0 {
+					if len(keys) > 1 {
+						return "", errors.Errorf("path %s denotes more than a column of table %s", n, pp.TableAlias)
+					}
+					return cg.Dialect.QuoteIdent(pp.TableAlias + "." + keys[0]), nil
 				}
+				return cg.Dialect.JsonPath(cg.Dialect.QuoteIdent(pp.JsonColumn), keys), nil
 			}
-		} else if cg.RenderingOptions.NamedParamsEnabled() {
-			v, _ := cg.RenderingOptions.GetNamedParamValues()
-			paramName := fmt.Sprintf("%s%d", cg.RenderingOptions.GetNamedParamsPrefix(), len(v)+1)
-			v[paramName] = f
-			return ":" + paramName, nil
 		}
+		return cg.Dialect.QuoteIdent(n), nil
+	}
+
+	if b := cg.binder(); b != nil {
+		v, err := toValueIn(f, t, cg.location())
+		if err != nil {
+			return "", err
+		}
+		return b(v), nil
+	}
+
+	switch t {
+	case dateType, timeType:
+		if _, err := toValue(f, t); err != nil {
+			return "", err
+		}
+	case dateTimeType:
+		s, err := toUTCDateTime(f, cg.location())
+		if err != nil {
+			return "", err
+		}
+		f = strings.Replace(s, "T", " ", 1)
+	}
+
+	return cg.toLiteral(f, t), nil
+}
+
+// location returns the time zone in which date and datetime literals without
+// a time zone offset are interpreted.
+func (cg *SqlCodeGenerator) location() *time.Location {
+	if cg.RenderingOptions == nil {
+		return time.UTC
+	}
+
+	return cg.RenderingOptions.GetLocation()
+}
+
+// binder returns the Binder that binds values to named parameters, or nil if
+// named parameters are not enabled.
+func (cg *SqlCodeGenerator) binder() Binder {
+	if cg.RenderingOptions == nil || !cg.RenderingOptions.NamedParamsEnabled() {
+		return nil
+	}
+
+	return cg.bind
+}
+
+// bind binds v to a new named parameter and returns a marker that stands for
+// its placeholder until rendering is complete.
+func (cg *SqlCodeGenerator) bind(v interface{}) string {
+	cg.pending = append(cg.pending, v)
+	return fmt.Sprintf("\x00%s:%d\x00", cg.marker, len(cg.pending)-1)
+}
+
+// resolvePlaceholders replaces the placeholder markers in s with actual
+// placeholders and turns the bound values into named parameters, in the order
+// the markers appear in s. Values are bound in rendering order, which differs
+// from the order of appearance when expressions are nested, e.g. in DATEADD.
+func (cg *SqlCodeGenerator) resolvePlaceholders(s string) *RenderResult {
+	rr := &RenderResult{}
+
+	if len(cg.pending) == 0 {
+		rr.Code = s
+		return rr
+	}
+
+	placeholderMarker := regexp.MustCompile(`\x00` + cg.marker + `:([0-9]+)\x00`)
+
+	rr.Code = placeholderMarker.ReplaceAllStringFunc(s, func(m string) string {
+		i, err := strconv.Atoi(placeholderMarker.FindStringSubmatch(m)[1])
+		if err != nil || i >= len(cg.pending) {
+			return m
+		}
+		pos := len(rr.NamedParams) + 1
+		name := cg.RenderingOptions.namedParamName(pos)
+		rr.NamedParams = append(rr.NamedParams, NamedParam{name, cg.pending[i]})
+		if p, ok := cg.RenderingOptions.GetPlaceholderStyle().placeholder(name, pos); ok {
+			return p
+		}
+		return cg.Dialect.Placeholder(name, pos)
+	})
+
+	return rr
+}
+
+// toLiteral renders the raw value v of type t as a literal.
+func (cg *SqlCodeGenerator) toLiteral(v string, t termType) string {
+	switch t {
+	case stringType, dateType, timeType, dateTimeType:
+		v = cg.Dialect.QuoteString(v)
+	case boolType:
+		v = cg.Dialect.Bool(v == "true")
 	}
 
-	return f, nil
+	return v
 }
@@ -8,14 +8,24 @@ package espressopp
 
 import (
 	"bytes"
+	"database/sql"
+	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestGenerateSql tests the generation of SQL from Espresso++ expressions.
 func TestGenerateSql(t *testing.T) {
 	interpreter := NewEspressoppInterpreter()
 	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.AddFieldProps("restricted", &FieldProps{Filterable: true, Operators: []string{"eq", "in"}})
+	codeGenerator.RenderingOptions.AddFieldProps("computed", &FieldProps{Filterable: true, NativeExpr: "first || ' ' || last"})
+	codeGenerator.RenderingOptions.AddPathProps("json", &PathProps{JsonColumn: "data"})
+	codeGenerator.RenderingOptions.AddPathProps("joined", &PathProps{TableAlias: "j"})
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
 
 	for _, item := range getTestDataItems() {
 		r := strings.NewReader(item.input)
@@ -39,3 +49,352 @@ func TestGenerateSql(t *testing.T) {
 		}
 	}
 }
+
+// TestGenerateSqlWithTypedFields tests the type checking of fields with a
+// declared type.
+func TestGenerateSqlWithTypedFields(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.Fields(getTypedFields())
+
+	for _, item := range getTypedFieldsTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateSqlWithNamedParams tests the generation of SQL with named
+// parameters from Espresso++ expressions.
+func TestGenerateSqlWithNamedParams(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for _, item := range getNamedParamsTestDataItems() {
+		dialect, _ := GetDialect(item.dialect)
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+		codeGenerator.RenderingOptions.EnableNamedParams()
+		codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+		params, _ := codeGenerator.RenderingOptions.GetNamedParams()
+
+		if err != nil {
+			t.Errorf("Interpreter with input '%v' : FAILED, %v", item.input, err)
+		} else if result != item.result || !reflect.DeepEqual(params, item.params) {
+			t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' %v but got '%v' %v", item.input, item.result, item.params, result, params)
+		} else {
+			t.Logf("Interpreter with input '%v' : PASSED, expected '%v' %v and got '%v' %v", item.input, item.result, item.params, result, params)
+		}
+	}
+}
+
+// TestGenerateSqlWithClock tests the generation of SQL from Espresso++
+// expressions whose macros and literals are resolved relative to a clock and
+// time zone.
+func TestGenerateSqlWithClock(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.SetClock(getClock())
+	codeGenerator.RenderingOptions.SetLocation(getLocation())
+	codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+	for _, item := range getClockTestDataItems()["sql"] {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
+
+// TestGenerateSqlWithClockAndNamedParams tests the binding of macros and
+// literals resolved relative to a clock and time zone to named parameters.
+func TestGenerateSqlWithClockAndNamedParams(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for _, item := range getClockNamedParamsTestDataItems() {
+		dialect, _ := GetDialect(item.dialect)
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+		codeGenerator.RenderingOptions.EnableNamedParams()
+		codeGenerator.RenderingOptions.SetClock(getClock())
+		codeGenerator.RenderingOptions.SetLocation(getLocation())
+		codeGenerator.RenderingOptions.SetMacroRegistry(getMacroRegistry())
+
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+		params, _ := codeGenerator.RenderingOptions.GetNamedParams()
+
+		if err != nil {
+			t.Errorf("Interpreter with input '%v' : FAILED, %v", item.input, err)
+		} else if result != item.result || !reflect.DeepEqual(params, item.params) {
+			t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' %v but got '%v' %v", item.input, item.result, item.params, result, params)
+		} else {
+			t.Logf("Interpreter with input '%v' : PASSED, expected '%v' %v and got '%v' %v", item.input, item.result, item.params, result, params)
+		}
+	}
+}
+
+// TestGenerateSqlWithEasternLocationAndNamedParams tests that dates bound to
+// named parameters denote the same calendar day as the literals they come from
+// when the time zone is east of UTC.
+func TestGenerateSqlWithEasternLocationAndNamedParams(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	loc := time.FixedZone("CEST", 2*60*60)
+
+	for _, item := range []namedParamsTestDataItem{
+		{testDataItem{"ident eq '2020-01-01'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident eq #today", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 19, 0, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident gte #startof('day')", "ident >= :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 19, 0, 0, 0, 0, loc)}}},
+		{testDataItem{"ident eq '2020-01-01T00:00:00'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 1, 1, 0, 0, 0, 0, loc)}}},
+	} {
+		dialect, _ := GetDialect(item.dialect)
+		codeGenerator := NewSqlCodeGeneratorWithDialect(dialect)
+		codeGenerator.RenderingOptions.EnableNamedParams()
+		codeGenerator.RenderingOptions.SetClock(getClock())
+		codeGenerator.RenderingOptions.SetLocation(loc)
+
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+		params, _ := codeGenerator.RenderingOptions.GetNamedParams()
+
+		if err != nil {
+			t.Errorf("Interpreter with input '%v' : FAILED, %v", item.input, err)
+		} else if result != item.result || !reflect.DeepEqual(params, item.params) {
+			t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' %v but got '%v' %v", item.input, item.result, item.params, result, params)
+		} else {
+			t.Logf("Interpreter with input '%v' : PASSED, expected '%v' %v and got '%v' %v", item.input, item.result, item.params, result, params)
+		}
+	}
+}
+
+// TestNamedParamArgs tests the conversion of named parameters into arguments
+// for database/sql.
+func TestNamedParamArgs(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.EnableNamedParams()
+
+	r := strings.NewReader("ident1 eq 'text' and ident2 gt 10 and ident3 eq false")
+	w := new(bytes.Buffer)
+
+	if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+		t.Fatalf("Interpreter : FAILED, %v", err)
+	}
+
+	args, _ := codeGenerator.RenderingOptions.Args()
+	if expected := []interface{}{"text", int64(10), false}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("Args : FAILED, expected %v but got %v", expected, args)
+	}
+
+	namedArgs, _ := codeGenerator.RenderingOptions.NamedArgs()
+	if expected := []sql.NamedArg{sql.Named("P1", "text"), sql.Named("P2", int64(10)), sql.Named("P3", false)}; !reflect.DeepEqual(namedArgs, expected) {
+		t.Errorf("NamedArgs : FAILED, expected %v but got %v", expected, namedArgs)
+	}
+
+	if expected := []interface{}{sql.Named("P1", "text"), sql.Named("P2", int64(10)), sql.Named("P3", false)}; !reflect.DeepEqual(ToArgs(namedArgs), expected) {
+		t.Errorf("ToArgs : FAILED, expected %v but got %v", expected, ToArgs(namedArgs))
+	}
+
+	values, _ := codeGenerator.RenderingOptions.GetNamedParamValues()
+	if expected := map[string]interface{}{"P1": "text", "P2": int64(10), "P3": false}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("GetNamedParamValues : FAILED, expected %v but got %v", expected, values)
+	}
+}
+
+// TestPlaceholderStyles tests the rendering of named parameters with the
+// supported placeholder styles.
+func TestPlaceholderStyles(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	for style, result := range map[PlaceholderStyle]string{
+		DialectPlaceholders:  "ident1 = :P1 AND ident2 > :P2",
+		ColonPlaceholders:    "ident1 = :P1 AND ident2 > :P2",
+		DollarPlaceholders:   "ident1 = $1 AND ident2 > $2",
+		QuestionPlaceholders: "ident1 = ? AND ident2 > ?",
+		AtPlaceholders:       "ident1 = @P1 AND ident2 > @P2",
+	} {
+		codeGenerator := NewSqlCodeGenerator()
+		codeGenerator.RenderingOptions.EnableNamedParams()
+		codeGenerator.RenderingOptions.SetPlaceholderStyle(style)
+
+		r := strings.NewReader("ident1 eq 'text' and ident2 gt 10")
+		w := new(bytes.Buffer)
+
+		if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+			t.Errorf("Placeholder style %v : FAILED, %v", style, err)
+		} else if w.String() != result {
+			t.Errorf("Placeholder style %v : FAILED, expected '%v' but got '%v'", style, result, w.String())
+		}
+	}
+}
+
+// TestPlaceholderOrder tests that named parameters are listed in the order
+// their placeholders appear, even when expressions are rendered inside out.
+func TestPlaceholderOrder(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGeneratorWithDialect(NewSqlServerDialect())
+	codeGenerator.RenderingOptions.EnableNamedParams()
+	codeGenerator.RenderingOptions.SetPlaceholderStyle(QuestionPlaceholders)
+
+	r := strings.NewReader("ident lt ('2020-03-15T14:10:25' add #duration('P1DT2H'))")
+	w := new(bytes.Buffer)
+
+	if err := interpreter.Accept(codeGenerator, r, w); err != nil {
+		t.Fatalf("Interpreter : FAILED, %v", err)
+	}
+
+	if expected := "ident < (DATEADD(HOUR, ?, DATEADD(DAY, ?, ?)))"; w.String() != expected {
+		t.Errorf("Interpreter : FAILED, expected '%v' but got '%v'", expected, w.String())
+	}
+
+	args, _ := codeGenerator.RenderingOptions.Args()
+	if expected := []interface{}{int64(2), int64(1), time.Date(2020, 3, 15, 14, 10, 25, 0, time.UTC)}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("Args : FAILED, expected %v but got %v", expected, args)
+	}
+}
+
+// TestGenerateConcurrently tests the generation of SQL with named parameters
+// from multiple goroutines sharing the same code generator.
+func TestGenerateConcurrently(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.EnableNamedParams()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		grammar, err := interpreter.Parse(strings.NewReader(fmt.Sprintf("ident1 eq %d and ident2 eq 'text'", i)))
+		if err != nil {
+			t.Fatalf("Parser : FAILED, %v", err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				rr, err := codeGenerator.Generate(grammar)
+				if err != nil {
+					t.Errorf("Generate : FAILED, %v", err)
+					return
+				}
+
+				expected := []NamedParam{{"P1", int64(i)}, {"P2", "text"}}
+				if rr.Code != "ident1 = :P1 AND ident2 = :P2" || !reflect.DeepEqual(rr.NamedParams, expected) {
+					t.Errorf("Generate : FAILED, expected %v but got '%v' %v", expected, rr.Code, rr.NamedParams)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// TestForgedPlaceholderMarkers tests that string literals that look like the
+// markers standing for placeholders are rendered as is.
+func TestForgedPlaceholderMarkers(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+
+	grammar, err := interpreter.Parse(strings.NewReader(`ident eq 'a\x000\x00b' and ident2 eq 'c\u00000\u0000'`))
+	if err != nil {
+		t.Fatalf("Parser : FAILED, %v", err)
+	}
+
+	for name, codeGenerator := range map[string]*SqlCodeGenerator{
+		"default":      NewSqlCodeGenerator(),
+		"nil options":  {Dialect: NewGenericDialect()},
+		"named params": NewSqlCodeGenerator(),
+	} {
+		if name == "named params" {
+			codeGenerator.RenderingOptions.EnableNamedParams()
+		}
+
+		rr, err := codeGenerator.Generate(grammar)
+		if err != nil {
+			t.Errorf("Generate with %v : FAILED, %v", name, err)
+			continue
+		}
+
+		var expected *RenderResult
+		if name == "named params" {
+			expected = &RenderResult{Code: "ident = :P1 AND ident2 = :P2", NamedParams: []NamedParam{{"P1", "a\x000\x00b"}, {"P2", "c\x000\x00"}}}
+		} else {
+			expected = &RenderResult{Code: "ident = 'a\x000\x00b' AND ident2 = 'c\x000\x00'"}
+		}
+
+		if !reflect.DeepEqual(rr, expected) {
+			t.Errorf("Generate with %v : FAILED, expected %q %v but got %q %v", name, expected.Code, expected.NamedParams, rr.Code, rr.NamedParams)
+		}
+	}
+}
+
+// TestGenerateSqlAtMonthEnd tests the generation of SQL from Espresso++
+// expressions whose calendar macros are resolved on the last day of a month.
+func TestGenerateSqlAtMonthEnd(t *testing.T) {
+	interpreter := NewEspressoppInterpreter()
+	codeGenerator := NewSqlCodeGenerator()
+	codeGenerator.RenderingOptions.SetClock(getMonthEndClock())
+
+	for _, item := range getMonthEndTestDataItems() {
+		r := strings.NewReader(item.input)
+		w := new(bytes.Buffer)
+		err := interpreter.Accept(codeGenerator, r, w)
+
+		result := w.String()
+
+		if item.hasError {
+			if err == nil {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected an error but got '%v'", item.input, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected an error and got '%v'", item.input, err)
+			}
+		} else {
+			if result != item.result {
+				t.Errorf("Interpreter with input '%v' : FAILED, expected '%v' but got '%v'", item.input, item.result, result)
+			} else {
+				t.Logf("Interpreter with input '%v' : PASSED, expected '%v' and got '%v'", item.input, item.result, result)
+			}
+		}
+	}
+}
@@ -6,6 +6,11 @@
 
 package espressopp
 
+import (
+	"fmt"
+	"time"
+)
+
 // testDataItem defines test data.
 type testDataItem struct {
 	input    string // input data
@@ -13,6 +18,13 @@ type testDataItem struct {
 	hasError bool   // whether the test returned an error
 }
 
+// namedParamsTestDataItem defines test data for named parameters.
+type namedParamsTestDataItem struct {
+	testDataItem
+	dialect string       // name of the dialect
+	params  []NamedParam // named parameters
+}
+
 // getTestDataItems returns an array of testDataItem structs with predefined
 // test data.
 func getTestDataItems() []testDataItem {
@@ -36,20 +48,107 @@ func getTestDataItems() []testDataItem {
 		{"ident lt 10", "ident < 10", false},
 		{"ident lte 10", "ident <= 10", false},
 		{"ident between 1 and 10", "ident BETWEEN 1 AND 10", false},
+		{"ident1 gt ident2", "ident1 > ident2", false},
+		{"ident1 lte ident2 add 1", "ident1 <= ident2 + 1", false},
+		{"ident between ident1 and ident2", "ident BETWEEN ident1 AND ident2", false},
 		{"ident gt 'text'", "ident > text", true},
 		{"ident between 'text1' and 'text2'", "ident BETWEEN 'text1' AND 'text2'", true},
 
-		{"ident startswith 'text'", "ident LIKE 'text%'", false},
-		{"ident endswith 'text'", "ident LIKE '%text'", false},
-		{"ident contains 'text'", "ident LIKE '%text%'", false},
+		{"ident startswith 'text'", `ident LIKE 'text%' ESCAPE '\'`, false},
+		{"ident endswith 'text'", `ident LIKE '%text' ESCAPE '\'`, false},
+		{"ident contains 'text'", `ident LIKE '%text%' ESCAPE '\'`, false},
 		{"ident startswith 1", "ident LIKE %1", true},
 		{"ident endswith '2020-03-15'", "ident LIKE '%2020-03-15'", true},
 		{"ident contains ident", "ident LIKE %ident", true},
+		{"ident contains '50%_off'", `ident LIKE '%50\%\_off%' ESCAPE '\'`, false},
+		{"ident istartswith 'text'", `LOWER(ident) LIKE LOWER('text%') ESCAPE '\'`, false},
+		{"ident iendswith 'text'", `LOWER(ident) LIKE LOWER('%text') ESCAPE '\'`, false},
+		{"ident icontains 'te_xt'", `LOWER(ident) LIKE LOWER('%te\_xt%') ESCAPE '\'`, false},
+		{"ident ieq 'te_xt'", "LOWER(ident) = LOWER('te_xt')", false},
+		{"ident ieq 1", "", true},
+		{"ident ieq ident2", "", true},
+		{"ident matches '^te.t$'", "", true},
+		{"ident eq 'it\\'s'", "ident = 'it''s'", false},
+
+		{"ident in ('text1', 'text2')", "ident IN ('text1', 'text2')", false},
+		{"ident not in (1, 2, 3)", "ident NOT IN (1, 2, 3)", false},
+		{"ident in ('2020-03-15', '2020-03-16')", "ident IN (DATE '2020-03-15', DATE '2020-03-16')", false},
+		{"ident1 add 1 in (ident2, 10)", "ident1 + 1 IN (ident2, 10)", false},
+		{"ident in (1, 'text')", "", true},
+		{"ident add 1 in ('text')", "", true},
+		{"ident in ()", "", true},
+
+		{"tags has 'vip'", "EXISTS (SELECT 1 FROM UNNEST(tags) AS e(elem) WHERE elem = 'vip')", false},
+		{"roles any in ('admin', 'owner')", "EXISTS (SELECT 1 FROM UNNEST(roles) AS e(elem) WHERE elem IN ('admin', 'owner'))", false},
+		{"roles all in ('admin', 'owner')", "NOT EXISTS (SELECT 1 FROM UNNEST(roles) AS e(elem) WHERE NOT (elem IN ('admin', 'owner')))", false},
+		{"scores all gt 50", "NOT EXISTS (SELECT 1 FROM UNNEST(scores) AS e(elem) WHERE NOT (elem > 50))", false},
+		{"scores any neq ident", "EXISTS (SELECT 1 FROM UNNEST(scores) AS e(elem) WHERE elem <> ident)", false},
+		{"scores any gt 'text'", "", true},
+		{"scores all in ()", "", true},
+		{"tags has", "", true},
+		{"restricted has 'text'", "", true},
+
+		{"lower(ident) eq 'text'", "LOWER(ident) = 'text'", false},
+		{"upper(ident) startswith 'TE'", `UPPER(ident) LIKE 'TE%' ESCAPE '\'`, false},
+		{"length(trim(ident)) gt 10", "CHAR_LENGTH(TRIM(ident)) > 10", false},
+		{"abs(ident1 sub ident2) lte 1", "ABS(ident1 - ident2) <= 1", false},
+		{"round(ident, 2) eq 1", "ROUND(ident, 2) = 1", false},
+		{"floor(ident) lt ceil(ident) sub 1", "FLOOR(ident) < CEIL(ident) - 1", false},
+		{"coalesce(ident1, ident2, 'text') eq 'text'", "COALESCE(ident1, ident2, 'text') = 'text'", false},
+		{"year(ident) eq 2020", "EXTRACT(YEAR FROM ident) = 2020", false},
+		{"month('2020-03-15') eq 3", "EXTRACT(MONTH FROM DATE '2020-03-15') = 3", false},
+		{"hour('14:10:25') eq 14", "EXTRACT(HOUR FROM TIME '14:10:25') = 14", false},
+		{"day('14:10:25') eq 14", "", true},
+		{"ident in (lower('TEXT'), 'text')", "ident IN (LOWER('TEXT'), 'text')", false},
+		{"lower(restricted) eq 'text'", "LOWER(restricted) = 'text'", false},
+		{"lower(restricted) startswith 'text'", "", true},
+		{"dayofweek(ident) eq 1", "", true},
+		{"lower(1) eq 'text'", "", true},
+		{"lower(ident) eq 1", "", true},
+		{"lower(ident1, ident2) eq 'text'", "", true},
+		{"coalesce(ident) eq 1", "", true},
+		{"coalesce(ident, 1, 'text') eq 1", "", true},
+		{"round(ident, 'text') eq 1", "", true},
+		{"unknown(ident) eq 1", "", true},
+
+		{"ident eq #tenant_id", "ident = 42", false},
+		{"ident eq #current_user", "ident = 'jdoe'", false},
+		{"ident gte #fiscal_year_start(2020)", "ident >= '2019-10-01'", false},
+		{"ident gte #fiscal_year_start('2020')", "", true},
+		{"ident gte #fiscal_year_start(ident2)", "", true},
+		{"ident gte #fiscal_year_start", "", true},
+		{"ident eq #unknown", "", true},
+
+		{"restricted eq 'text'", "restricted = 'text'", false},
+		{"restricted not in ('text')", "restricted NOT IN ('text')", false},
+		{"restricted neq 'text'", "", true},
+		{"restricted contains 'text'", "", true},
+		{"ident eq 1 or not (restricted add 1 gt 10)", "", true},
+		{"is restricted", "", true},
+
+		{"computed eq 'text'", "(first || ' ' || last) = 'text'", false},
+		{"computed startswith 'text'", `(first || ' ' || last) LIKE 'text%' ESCAPE '\'`, false},
+		{"computed add 1 gt 10 or computed is null", "(first || ' ' || last) + 1 > 10 OR (first || ' ' || last) IS NULL", false},
+
+		{"t1.ident eq 1", "t1.ident = 1", false},
+		{"t1.order is not null", `t1."order" IS NOT NULL`, false},
+		{"json.address.city eq 'text'", "JSON_VALUE(data, '$.address.city') = 'text'", false},
+		{"is json.enabled", "JSON_VALUE(data, '$.enabled') = 1", false},
+		{"joined.ident in (1, 2)", "j.ident IN (1, 2)", false},
+		{"joined.ident.key eq 1", "", true},
+		{"t1. ident eq 1", "", true},
+
+		{"ident1 startswith 'text' and (ident2 eq 1 or ident2 gt 10)", `ident1 LIKE 'text%' ESCAPE '\' AND (ident2 = 1 OR ident2 > 10)`, false},
+		{"ident1 startswith 'text' or (ident2 gte 1 and ident2 lte 10)", `ident1 LIKE 'text%' ESCAPE '\' OR (ident2 >= 1 AND ident2 <= 10)`, false},
+		{"ident1 startswith 'text' and not (ident2 eq 1 or ident2 gt 10)", `ident1 LIKE 'text%' ESCAPE '\' AND NOT (ident2 = 1 OR ident2 > 10)`, false},
+		{"ident1 startswith 'text' or not (ident2 gte 1 and ident2 lte 10)", `ident1 LIKE 'text%' ESCAPE '\' OR NOT (ident2 >= 1 AND ident2 <= 10)`, false},
 
-		{"ident1 startswith 'text' and (ident2 eq 1 or ident2 gt 10)", "ident1 LIKE 'text%' AND (ident2 = 1 OR ident2 > 10)", false},
-		{"ident1 startswith 'text' or (ident2 gte 1 and ident2 lte 10)", "ident1 LIKE 'text%' OR (ident2 >= 1 AND ident2 <= 10)", false},
-		{"ident1 startswith 'text' and not (ident2 eq 1 or ident2 gt 10)", "ident1 LIKE 'text%' AND NOT (ident2 = 1 OR ident2 > 10)", false},
-		{"ident1 startswith 'text' or not (ident2 gte 1 and ident2 lte 10)", "ident1 LIKE 'text%' OR NOT (ident2 >= 1 AND ident2 <= 10)", false},
+		{"ident1 eq 1 or ident2 eq 2 and ident3 eq 3", "ident1 = 1 OR ident2 = 2 AND ident3 = 3", false},
+		{"ident1 eq 1 and and ident2 eq 2", "", true},
+		{"ident1 eq 1 ident2 eq 2", "", true},
+		{"and ident1 eq 1", "", true},
+		{"ident1 eq 1 or", "", true},
+		{"(ident1 eq 1 or ident2 eq 2", "", true},
 
 		{"ident1 eq (ident2 add 1)", "ident1 = (ident2 + 1)", false},
 		{"ident1 eq (ident2 sub 1)", "ident1 = (ident2 - 1)", false},
@@ -61,16 +160,685 @@ func getTestDataItems() []testDataItem {
 		{"ident1 eq ident2 mul 1", "ident1 = ident2 * 1", false},
 		{"ident1 eq ident2 div 1", "ident1 = ident2 / 1", false},
 		{"ident1 eq ident2 add 'text'", "ident1 = ident2 + 'text'", true},
+		{"ident1 eq ident2 add ident3 mul 2", "ident1 = ident2 + ident3 * 2", false},
+		{"ident1 eq (ident2 add ident3) div (ident4 sub 1)", "ident1 = (ident2 + ident3) / (ident4 - 1)", false},
+		{"ident1 eq ((ident2 add 1) mul 2)", "ident1 = ((ident2 + 1) * 2)", false},
+		{"(ident1 add 1) gt ident2 sub 1 sub 2", "(ident1 + 1) > ident2 - 1 - 2", false},
+		{"ident1 eq -ident2", "ident1 = -ident2", false},
+		{"ident1 lt -(ident2 mul 2)", "ident1 < -(ident2 * 2)", false},
+		{"ident1 eq ident2 sub -1", "ident1 = ident2 - -1", false},
+		{"ident1 eq ident2 add 1 mul 'text'", "", true},
+		{"ident1 eq -'text'", "", true},
 
 		{"ident eq '2020-03-15'", "ident = '2020-03-15'", false},
 		{"ident eq '15:30:55'", "ident = '15:30:55'", false},
-		{"ident eq '2020-03-15T14:10:25+02'", "ident = '2020-03-15 14:10:25+02'", false},
+		{"ident eq '2020-03-15T14:10:25+02'", "ident = '2020-03-15 12:10:25'", false},
+		{"ident eq '2020-03-15T14:10:25Z'", "ident = '2020-03-15 14:10:25'", false},
+		{"ident eq '2020-03-15t14:10:25z'", "ident = '2020-03-15 14:10:25'", false},
+		{"ident eq '2020-03-15T14:10:25-05:00'", "ident = '2020-03-15 19:10:25'", false},
+		{"ident eq '2020-03-15T14:10:25.5+05:30'", "ident = '2020-03-15 08:40:25.5'", false},
+		{"ident eq '2020-02-29'", "ident = '2020-02-29'", false},
+		{"ident eq '2021-02-29'", "", true},
+		{"ident eq '2020-02-30T14:10:25Z'", "", true},
+		{"ident eq '2020-03-15T25:10:25Z'", "", true},
+		{"ident eq '2020-03-15T14:10:25+24:00'", "", true},
+		{"ident eq '24:00:00'", "", true},
 
 		{"ident eq #now", "ident = CURRENT_TIMESTAMP", false},
+		{"ident eq #now(1)", "", true},
+		{"ident lt (#now('x') sub #duration('PT1H'))", "", true},
 		{"ident gt #now // this is a comment", "ident > CURRENT_TIMESTAMP", false},
 		{"ident lt (#now sub #duration('PT1H'))", "ident < (CURRENT_TIMESTAMP - INTERVAL '1 HOUR')", false},
 		{"ident lt (#now add #duration('PT2H'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '2 HOURS')", false},
 		{"ident lt ('2020-03-15T14:10:25' add #duration('PT2H'))", "ident < (TIMESTAMP '2020-03-15 14:10:25' + INTERVAL '2 HOURS')", false},
 		{"ident lt (#now add #duration)", "ident < (CURRENT_TIMESTAMP + INTERVAL)", true},
+		{"ident lt #now sub #duration('P1D') add #duration('PT1H')", "ident < CURRENT_TIMESTAMP - INTERVAL '1 DAY' + INTERVAL '1 HOUR'", false},
+		{"ident lt #duration('P1D') add ident2", "ident < ident2 + INTERVAL '1 DAY'", false},
+		{"ident lt (#now sub #duration('P1M'))", "ident < (CURRENT_TIMESTAMP - INTERVAL '1 MONTH')", false},
+		{"ident lt (#now add #duration('P1Y2M3W'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '1 YEAR 2 MONTHS 3 WEEKS')", false},
+		{"ident lt (#now add #duration('PT1.5S'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '1.5 SECONDS')", false},
+		{"ident lt (#now add #duration('-P1D'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '-1 DAYS')", false},
+		{"ident lt (#now add #duration('P0D'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '0 SECONDS')", false},
+		{"ident lt (#now add #duration('P1.5D'))", "", true},
+		{"ident lt (#now add #duration('P'))", "", true},
+		{"ident lt (#now add #duration('PT'))", "", true},
+		{"ident lt (#now add #duration('PT0.0000001S'))", "", true},
+	}
+}
+
+// getMongoTestDataItems returns an array of testDataItem structs with predefined
+// test data for MongoCodeGenerator.
+func getMongoTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"ident eq 10", `{"ident":{"$eq":10}}`, false},
+		{"ident eq 'text'", `{"ident":{"$eq":"text"}}`, false},
+		{"ident neq 10", `{"ident":{"$ne":10}}`, false},
+		{"10 lt ident", `{"ident":{"$gt":10}}`, false},
+		{"ident1 gt ident2", `{"$expr":{"$gt":["$ident1","$ident2"]}}`, false},
+		{"ident between ident1 and ident2", `{"$expr":{"$and":[{"$gte":["$ident","$ident1"]},{"$lte":["$ident","$ident2"]}]}}`, false},
+		{"mapped eq 10", `{"native":{"$eq":10}}`, false},
+		{"hidden eq 10", "", true},
+		{"restricted eq 1", `{"restricted":{"$eq":1}}`, false},
+		{"address.city eq 'text'", `{"address.city":{"$eq":"text"}}`, false},
+		{"address.number eq address.floor", `{"$expr":{"$eq":["$address.number","$address.floor"]}}`, false},
+		{"restricted gt 1", "", true},
+		{"ident eq 1 and restricted startswith 'text'", "", true},
+		{"computed eq 1", "", true},
+
+		{"tags has 'vip'", `{"tags":{"$elemMatch":{"$eq":"vip"}}}`, false},
+		{"roles any in ('admin', 'owner')", `{"roles":{"$elemMatch":{"$in":["admin","owner"]}}}`, false},
+		{"roles all in ('admin', 'owner')", `{"roles":{"$not":{"$elemMatch":{"$nin":["admin","owner"]}}}}`, false},
+		{"scores any gte 50", `{"scores":{"$elemMatch":{"$gte":50}}}`, false},
+		{"scores all gt 50", `{"scores":{"$not":{"$elemMatch":{"$lte":50}}}}`, false},
+		{"scores all gt 'text'", "", true},
+		{"scores any eq ident", "", true},
+
+		{"lower(ident) eq 'text'", `{"$expr":{"$eq":[{"$toLower":"$ident"},{"$literal":"text"}]}}`, false},
+		{"trim(ident) eq 'text'", `{"$expr":{"$eq":[{"$trim":{"input":"$ident"}},{"$literal":"text"}]}}`, false},
+		{"round(ident, 2) gt 1", `{"$expr":{"$gt":[{"$round":["$ident",2]},1]}}`, false},
+		{"coalesce(ident1, ident2) eq 1", `{"$expr":{"$eq":[{"$ifNull":["$ident1","$ident2"]},1]}}`, false},
+		{"day(ident) eq 1", `{"$expr":{"$eq":[{"$dayOfMonth":"$ident"},1]}}`, false},
+		{"ident in (lower('TEXT'))", "", true},
+		{"hour('14:10:25') eq 14", "", true},
+		{"length(ident) gt 'text'", "", true},
+
+		{"ident eq #tenant_id", `{"$expr":{"$eq":["$ident",42]}}`, false},
+		{"ident eq #current_user", `{"$expr":{"$eq":["$ident",{"$literal":"jdoe"}]}}`, false},
+		{"ident in (#current_user)", `{"ident":{"$in":["jdoe"]}}`, false},
+		{"ident eq #sql_only", "", true},
+		{"ident eq #unknown", "", true},
+
+		{"ident is true", `{"ident":{"$eq":true}}`, false},
+		{"ident is not false", `{"ident":{"$ne":false}}`, false},
+		{"ident is null", `{"ident":{"$eq":null}}`, false},
+		{"ident is not null", `{"ident":{"$exists":true,"$ne":null}}`, false},
+		{"is ident", `{"ident":{"$eq":true}}`, false},
+		{"is not mapped", `{"native":{"$eq":false}}`, false},
+
+		{"ident gte 10", `{"ident":{"$gte":10}}`, false},
+		{"ident lte 10", `{"ident":{"$lte":10}}`, false},
+		{"ident between 1 and 10", `{"ident":{"$gte":1,"$lte":10}}`, false},
+		{"ident gt 'text'", "", true},
+		{"ident between 'text1' and 'text2'", "", true},
+
+		{"ident startswith 'te.xt'", `{"ident":{"$regex":"^te\\.xt"}}`, false},
+		{"ident endswith 'text'", `{"ident":{"$regex":"text$"}}`, false},
+		{"ident contains 'text'", `{"ident":{"$regex":"text"}}`, false},
+		{"ident istartswith 'text'", `{"ident":{"$regex":"^text","$options":"i"}}`, false},
+		{"ident icontains 'text'", `{"ident":{"$regex":"text","$options":"i"}}`, false},
+		{"ident ieq 'te.xt'", `{"ident":{"$regex":"^te\\.xt$","$options":"i"}}`, false},
+		{"ident ieq 1", "", true},
+		{"ident matches '^te\\\\d+'", `{"ident":{"$regex":"^te\\d+"}}`, false},
+		{"ident matches 'te(xt'", "", true},
+		{"ident startswith 1", "", true},
+		{"ident contains ident", "", true},
+
+		{"ident in ('text1', 'text2')", `{"ident":{"$in":["text1","text2"]}}`, false},
+		{"ident not in (1, 2)", `{"ident":{"$nin":[1,2]}}`, false},
+		{"ident1 add 1 in (ident2, 10)", `{"$expr":{"$in":[{"$add":["$ident1",1]},["$ident2",10]]}}`, false},
+		{"ident1 add 1 not in (10)", `{"$expr":{"$not":[{"$in":[{"$add":["$ident1",1]},[10]]}]}}`, false},
+		{"ident in (1, 'text')", "", true},
+
+		{"ident1 eq 1 and ident2 eq 2 or ident3 eq 3", `{"$or":[{"$and":[{"ident1":{"$eq":1}},{"ident2":{"$eq":2}}]},{"ident3":{"$eq":3}}]}`, false},
+		{"ident1 startswith 'text' and (ident2 eq 1 or ident2 gt 10)", `{"$and":[{"ident1":{"$regex":"^text"}},{"$or":[{"ident2":{"$eq":1}},{"ident2":{"$gt":10}}]}]}`, false},
+		{"ident1 startswith 'text' and not (ident2 eq 1 or ident2 gt 10)", `{"$and":[{"ident1":{"$regex":"^text"}},{"$nor":[{"$or":[{"ident2":{"$eq":1}},{"ident2":{"$gt":10}}]}]}]}`, false},
+		{"ident1 eq 1 and and ident2 eq 2", "", true},
+		{"ident1 eq 1 ident2 eq 2", "", true},
+
+		{"ident1 eq ident2", `{"$expr":{"$eq":["$ident1","$ident2"]}}`, false},
+		{"ident1 eq (ident2 add 1)", `{"$expr":{"$eq":["$ident1",{"$add":["$ident2",1]}]}}`, false},
+		{"ident1 eq ident2 mul 1", `{"$expr":{"$eq":["$ident1",{"$multiply":["$ident2",1]}]}}`, false},
+		{"ident1 eq ident2 add 'text'", "", true},
+		{"ident between 1 and ident2 sub 1", `{"$expr":{"$and":[{"$gte":["$ident",1]},{"$lte":["$ident",{"$subtract":["$ident2",1]}]}]}}`, false},
+
+		{"ident eq '2020-03-15'", `{"ident":{"$eq":{"$date":"2020-03-15T00:00:00Z"}}}`, false},
+		{"ident eq '15:30:55'", `{"ident":{"$eq":"15:30:55"}}`, false},
+		{"ident eq '2020-03-15T14:10:25+02'", `{"ident":{"$eq":{"$date":"2020-03-15T12:10:25Z"}}}`, false},
+		{"ident eq '2020-03-15T14:10:25-05:00'", `{"ident":{"$eq":{"$date":"2020-03-15T19:10:25Z"}}}`, false},
+		{"ident eq '2020-02-30'", "", true},
+		{"ident eq '25:00:00'", "", true},
+
+		{"ident gt #now", `{"$expr":{"$gt":["$ident","$$NOW"]}}`, false},
+		{"ident gt #now(1)", "", true},
+		{"ident lt (#now('x') sub #duration('PT1H'))", "", true},
+		{"ident lt (#now sub #duration('PT1H'))", `{"$expr":{"$lt":["$ident",{"$subtract":["$$NOW",3600000]}]}}`, false},
+		{"ident lt (#now sub #duration('P1M'))", `{"$expr":{"$lt":["$ident",{"$dateSubtract":{"startDate":"$$NOW","unit":"month","amount":1}}]}}`, false},
+		{"ident lt (#now add #duration('P1MT1.5S'))", `{"$expr":{"$lt":["$ident",{"$dateAdd":{"startDate":{"$dateAdd":{"startDate":{"$dateAdd":{"startDate":"$$NOW","unit":"month","amount":1}},"unit":"second","amount":1}},"unit":"millisecond","amount":500}}]}}`, false},
+		{"ident lt (#now add #duration('-PT1H'))", `{"$expr":{"$lt":["$ident",{"$add":["$$NOW",-3600000]}]}}`, false},
+		{"ident lt (#now add #duration('PT1.5S'))", `{"$expr":{"$lt":["$ident",{"$add":["$$NOW",1500]}]}}`, false},
+		{"ident lt #duration('P1M')", "", true},
+		{"ident1 eq ident2 add ident3 mul 2", `{"$expr":{"$eq":["$ident1",{"$add":["$ident2",{"$multiply":["$ident3",2]}]}]}}`, false},
+		{"-ident gt (ident2 sub 1) div 2", `{"$expr":{"$gt":[{"$multiply":[-1,"$ident"]},{"$divide":[{"$subtract":["$ident2",1]},2]}]}}`, false},
+		{"ident lt (#now add #duration)", "", true},
+	}
+}
+
+// getElasticsearchTestDataItems returns an array of testDataItem structs with
+// predefined test data for ElasticsearchCodeGenerator.
+func getElasticsearchTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"ident eq 10", `{"term":{"ident":10}}`, false},
+		{"ident eq 'text'", `{"term":{"ident":"text"}}`, false},
+		{"ident neq 10", `{"bool":{"must_not":[{"term":{"ident":10}}]}}`, false},
+		{"10 lt ident", `{"range":{"ident":{"gt":10}}}`, false},
+		{"mapped eq 10", `{"term":{"native":10}}`, false},
+		{"hidden eq 10", "", true},
+		{"restricted in (1, 2)", `{"terms":{"restricted":[1,2]}}`, false},
+		{"address.city eq 'text'", `{"term":{"address.city":"text"}}`, false},
+		{"restricted between 1 and 2", "", true},
+		{"ident eq 1 or restricted ieq 'text'", "", true},
+		{"computed eq 1", "", true},
+		{"ident1 eq ident2", "", true},
+
+		{"tags has 'vip'", `{"term":{"tags":"vip"}}`, false},
+		{"roles any in ('admin', 'owner')", `{"terms":{"roles":["admin","owner"]}}`, false},
+		{"scores any gte 50", `{"range":{"scores":{"gte":50}}}`, false},
+		{"scores any gt 'text'", "", true},
+		{"scores any neq 50", "", true},
+		{"scores all gt 50", "", true},
+		{"lower(ident) eq 'text'", "", true},
+		{"ident eq lower('TEXT')", "", true},
+		{"ident eq #tenant_id", `{"term":{"ident":42}}`, false},
+		{"ident gte #fiscal_year_start(2020)", `{"range":{"ident":{"gte":"2019-10-01"}}}`, false},
+		{"ident eq #sql_only", "", true},
+		{"ident eq #unknown", "", true},
+
+		{"ident is true", `{"term":{"ident":true}}`, false},
+		{"ident is not false", `{"bool":{"must_not":[{"term":{"ident":false}}]}}`, false},
+		{"ident is null", `{"bool":{"must_not":[{"exists":{"field":"ident"}}]}}`, false},
+		{"ident is not null", `{"exists":{"field":"ident"}}`, false},
+		{"is ident", `{"term":{"ident":true}}`, false},
+		{"is not mapped", `{"term":{"native":false}}`, false},
+
+		{"ident gte 10", `{"range":{"ident":{"gte":10}}}`, false},
+		{"ident lte 10", `{"range":{"ident":{"lte":10}}}`, false},
+		{"ident between 1 and 10", `{"range":{"ident":{"gte":1,"lte":10}}}`, false},
+		{"ident gt 'text'", "", true},
+		{"ident between 'text1' and 'text2'", "", true},
+
+		{"ident startswith 'text'", `{"prefix":{"ident":"text"}}`, false},
+		{"ident endswith 'te*xt'", `{"wildcard":{"ident":"*te\\*xt"}}`, false},
+		{"ident contains 'text'", `{"wildcard":{"ident":"*text*"}}`, false},
+		{"ident istartswith 'text'", `{"prefix":{"ident":{"value":"text","case_insensitive":true}}}`, false},
+		{"ident iendswith 'te*xt'", `{"wildcard":{"ident":{"value":"*te\\*xt","case_insensitive":true}}}`, false},
+		{"ident ieq 'te*xt'", `{"term":{"ident":{"value":"te*xt","case_insensitive":true}}}`, false},
+		{"ident ieq 1", "", true},
+		{"ident matches '^text'", "", true},
+		{"ident startswith 1", "", true},
+		{"ident contains ident", "", true},
+
+		{"ident in ('text1', 'text2')", `{"terms":{"ident":["text1","text2"]}}`, false},
+		{"ident not in (1, 2)", `{"bool":{"must_not":[{"terms":{"ident":[1,2]}}]}}`, false},
+		{"ident1 add 1 in (1, 2)", "", true},
+		{"ident1 in (ident2)", "", true},
+		{"ident in (1, 'text')", "", true},
+
+		{"ident1 eq 1 and ident2 eq 2 or ident3 eq 3", `{"bool":{"should":[{"bool":{"filter":[{"term":{"ident1":1}},{"term":{"ident2":2}}]}},{"term":{"ident3":3}}],"minimum_should_match":1}}`, false},
+		{"ident1 startswith 'text' and not (ident2 eq 1 or ident2 gt 10)", `{"bool":{"filter":[{"prefix":{"ident1":"text"}},{"bool":{"must_not":[{"bool":{"should":[{"term":{"ident2":1}},{"range":{"ident2":{"gt":10}}}],"minimum_should_match":1}}]}}]}}`, false},
+		{"ident1 eq 1 and and ident2 eq 2", "", true},
+		{"ident1 eq 1 ident2 eq 2", "", true},
+
+		{"ident1 eq (ident2 add 1)", "", true},
+		{"ident eq '2020-03-15'", `{"term":{"ident":"2020-03-15"}}`, false},
+		{"ident eq '2020-03-15T14:10:25+02'", `{"term":{"ident":"2020-03-15T14:10:25+02:00"}}`, false},
+		{"ident eq '2020-03-15T14:10:25-05:00'", `{"term":{"ident":"2020-03-15T14:10:25-05:00"}}`, false},
+		{"ident eq '2020-03-15T14:10:25z'", `{"term":{"ident":"2020-03-15T14:10:25Z"}}`, false},
+		{"ident eq '2020-02-30'", "", true},
+		{"ident lt ('2020-02-30' add #duration('P1W'))", "", true},
+
+		{"ident eq #now", `{"range":{"ident":{"gte":"now","lte":"now"}}}`, false},
+		{"#now neq ident", `{"bool":{"must_not":[{"range":{"ident":{"gte":"now","lte":"now"}}}]}}`, false},
+		{"ident eq (#now sub #duration('P1D'))", `{"range":{"ident":{"gte":"now-1d","lte":"now-1d"}}}`, false},
+		{"ident eq ('2020-03-15' add #duration('P1D'))", `{"range":{"ident":{"gte":"2020-03-15||+1d","lte":"2020-03-15||+1d"}}}`, false},
+		{"ident eq 'now'", `{"term":{"ident":"now"}}`, false},
+		{"dates has #now", `{"range":{"dates":{"gte":"now","lte":"now"}}}`, false},
+		{"dates any eq #now", `{"range":{"dates":{"gte":"now","lte":"now"}}}`, false},
+		{"ident in (#now)", "", true},
+		{"dates any in (#now)", "", true},
+		{"ident gt #now", `{"range":{"ident":{"gt":"now"}}}`, false},
+		{"ident gt #now(1)", "", true},
+		{"ident lt (#now('x') sub #duration('PT1H'))", "", true},
+		{"ident lt (#now sub #duration('PT2H'))", `{"range":{"ident":{"lt":"now-2h"}}}`, false},
+		{"ident lt #now add #duration('P1DT2H')", `{"range":{"ident":{"lt":"now+1d+2h"}}}`, false},
+		{"ident lt #now sub #duration('P1D') add #duration('PT1H')", `{"range":{"ident":{"lt":"now-1d+1h"}}}`, false},
+		{"ident lt #now add #duration('P1M')", `{"range":{"ident":{"lt":"now+1M"}}}`, false},
+		{"ident lt #now add #duration('-P1D')", `{"range":{"ident":{"lt":"now-1d"}}}`, false},
+		{"ident lt #now add #duration('PT1.5S')", "", true},
+		{"ident lt (ident2 add 1) mul 2", "", true},
+		{"ident lt ('2020-03-15' add #duration('P1W'))", `{"range":{"ident":{"lt":"2020-03-15||+1w"}}}`, false},
+		{"ident lt #duration('PT2H')", "", true},
+		{"ident lt (#now add #duration)", "", true},
+	}
+}
+
+// getTypedFieldsTestDataItems returns an array of testDataItem structs with
+// predefined test data for the fields declared by getTypedFields.
+func getTypedFieldsTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"age eq 10", "age = 10", false},
+		{"age eq 'text'", "", true},
+		{"age eq price", "age = price", false},
+		{"price gt 10", "price > 10", false},
+		{"age gt price", "age > price", false},
+		{"name gt age", "", true},
+		{"price add 'text' lt 1", "", true},
+		{"price mul 2 lt .5", "price * 2 < 0.5", false},
+		{"age between 1 and 10", "age BETWEEN 1 AND 10", false},
+		{"created between '2020-01-01' and '2020-02-01'", "created BETWEEN '2020-01-01' AND '2020-02-01'", false},
+		{"-age lt 1", "-age < 1", false},
+		{"created gt 10", "", true},
+		{"created gt '2020-03-15'", "created > '2020-03-15'", false},
+		{"created lt (#now sub #duration('P1D'))", "created < (CURRENT_TIMESTAMP - INTERVAL '1 DAY')", false},
+		{"created lt (age add #duration('P1D'))", "", true},
+		{"name startswith 'text'", `name LIKE 'text%' ESCAPE '\'`, false},
+		{"name eq 1", "", true},
+		{"id eq '123e4567-e89b-12d3-a456-426614174000'", "id = '123e4567-e89b-12d3-a456-426614174000'", false},
+		{"id gt 'text'", "", true},
+		{"id startswith 'text'", "", true},
+		{"status in ('open', 'closed')", "status IN ('open', 'closed')", false},
+		{"status in (1, 2)", "", true},
+		{"is active", "active = 1", false},
+		{"active is not true", "active != 1", false},
+		{"active eq 1", "", true},
+		{"is age", "", true},
+		{"age is null", "age IS NULL", false},
+		{"untyped eq 'text'", "untyped = 'text'", false},
+		{"priority eq 'high'", "priority = 3", false},
+		{"'low' neq priority", "1 <> priority", false},
+		{"priority in ('low', 'high')", "priority IN (1, 3)", false},
+		{"priority eq 'hihg'", "", true},
+		{"priority in ('low', 'hihg')", "", true},
+		{"priority eq 3", "", true},
+		{"priority gt 'low'", "", true},
+		{"gender eq 'f'", "gender = 'f'", false},
+		{"gender not in ('m')", "gender NOT IN ('male')", false},
+		{"gender eq 'x'", "", true},
+		{"tags has 'vip'", "EXISTS (SELECT 1 FROM UNNEST(tags) AS e(elem) WHERE elem = 'vip')", false},
+		{"tags has 1", "", true},
+		{"tags eq 'vip'", "", true},
+		{"tags any gt 'text'", "", true},
+		{"scores all gte 50", "NOT EXISTS (SELECT 1 FROM UNNEST(scores) AS e(elem) WHERE NOT (elem >= 50))", false},
+		{"scores any in (1, 'text')", "", true},
+		{"levels any in ('low', 'high')", "EXISTS (SELECT 1 FROM UNNEST(levels) AS e(elem) WHERE elem IN (1, 2))", false},
+		{"levels has 'hihg'", "", true},
+		{"age has 1", "", true},
+		{"untyped has 1", "EXISTS (SELECT 1 FROM UNNEST(untyped) AS e(elem) WHERE elem = 1)", false},
+		{"abs(age) eq 1", "ABS(age) = 1", false},
+		{"abs(age) eq 'text'", "", true},
+		{"year(created) eq 2020", "EXTRACT(YEAR FROM created) = 2020", false},
+		{"year(name) eq 2020", "", true},
+		{"coalesce(age, price) gt 1", "COALESCE(age, price) > 1", false},
+		{"coalesce(name, age) eq 1", "", true},
+		{"length(name) gt 'text'", "", true},
+	}
+}
+
+// getMongoTypedFieldsTestDataItems returns an array of testDataItem structs
+// with predefined test data for the fields declared by getTypedFields.
+func getMongoTypedFieldsTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"priority eq 'high'", `{"priority":{"$eq":3}}`, false},
+		{"'low' neq priority", `{"priority":{"$ne":1}}`, false},
+		{"priority in ('low', 'high')", `{"priority":{"$in":[1,3]}}`, false},
+		{"priority eq 'hihg'", "", true},
+		{"priority in ('low', 'hihg')", "", true},
+		{"priority gt 'low'", "", true},
+		{"gender eq 'f'", `{"gender":{"$eq":"f"}}`, false},
+		{"gender not in ('m')", `{"gender":{"$nin":["male"]}}`, false},
+		{"gender eq 'x'", "", true},
+		{"levels has 'high'", `{"levels":{"$elemMatch":{"$eq":2}}}`, false},
+		{"levels any in ('low', 'high')", `{"levels":{"$elemMatch":{"$in":[1,2]}}}`, false},
+		{"levels has 'hihg'", "", true},
+		{"age eq 10", `{"age":{"$eq":10}}`, false},
+		{"age eq 'text'", "", true},
+		{"price gt 10", `{"price":{"$gt":10}}`, false},
+		{"name gt age", "", true},
+		{"age between 1 and 10", `{"age":{"$gte":1,"$lte":10}}`, false},
+		{"age between 'text1' and 'text2'", "", true},
+		{"created between '2020-01-01' and '2020-02-01'", `{"created":{"$gte":{"$date":"2020-01-01T00:00:00Z"},"$lte":{"$date":"2020-02-01T00:00:00Z"}}}`, false},
+		{"name startswith 'text'", `{"name":{"$regex":"^text"}}`, false},
+		{"id startswith 'text'", "", true},
+		{"status in (1, 2)", "", true},
+		{"is active", `{"active":{"$eq":true}}`, false},
+		{"is age", "", true},
+		{"age is null", `{"age":{"$eq":null}}`, false},
+		{"tags eq 'vip'", "", true},
+		{"tags has 1", "", true},
+		{"scores any gte 50", `{"scores":{"$elemMatch":{"$gte":50}}}`, false},
+		{"age has 1", "", true},
+	}
+}
+
+// getElasticsearchTypedFieldsTestDataItems returns an array of testDataItem
+// structs with predefined test data for the fields declared by getTypedFields.
+func getElasticsearchTypedFieldsTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"priority eq 'high'", `{"term":{"priority":3}}`, false},
+		{"'low' neq priority", `{"bool":{"must_not":[{"term":{"priority":1}}]}}`, false},
+		{"priority in ('low', 'high')", `{"terms":{"priority":[1,3]}}`, false},
+		{"priority eq 'hihg'", "", true},
+		{"priority in ('low', 'hihg')", "", true},
+		{"priority gt 'low'", "", true},
+		{"gender eq 'f'", `{"term":{"gender":"f"}}`, false},
+		{"gender not in ('m')", `{"bool":{"must_not":[{"terms":{"gender":["male"]}}]}}`, false},
+		{"gender eq 'x'", "", true},
+		{"levels has 'high'", `{"term":{"levels":2}}`, false},
+		{"levels any in ('low', 'high')", `{"terms":{"levels":[1,2]}}`, false},
+		{"levels has 'hihg'", "", true},
+		{"age eq 10", `{"term":{"age":10}}`, false},
+		{"age eq 'text'", "", true},
+		{"price gt 10", `{"range":{"price":{"gt":10}}}`, false},
+		{"name gt age", "", true},
+		{"age between 1 and 10", `{"range":{"age":{"gte":1,"lte":10}}}`, false},
+		{"age between 'text1' and 'text2'", "", true},
+		{"created between '2020-01-01' and '2020-02-01'", `{"range":{"created":{"gte":"2020-01-01","lte":"2020-02-01"}}}`, false},
+		{"name startswith 'text'", `{"prefix":{"name":"text"}}`, false},
+		{"id startswith 'text'", "", true},
+		{"status in (1, 2)", "", true},
+		{"is active", `{"term":{"active":true}}`, false},
+		{"is age", "", true},
+		{"age is null", `{"bool":{"must_not":[{"exists":{"field":"age"}}]}}`, false},
+		{"tags eq 'vip'", "", true},
+		{"tags has 1", "", true},
+		{"scores any gte 50", `{"range":{"scores":{"gte":50}}}`, false},
+		{"age has 1", "", true},
+	}
+}
+
+// getTypedFields returns the typed fields used by getTypedFieldsTestDataItems.
+func getTypedFields() map[string]*FieldProps {
+	return map[string]*FieldProps{
+		"age":      {Filterable: true, Type: IntField},
+		"price":    {Filterable: true, Type: DecimalField},
+		"created":  {Filterable: true, Type: DateTimeField},
+		"name":     {Filterable: true, Type: StringField},
+		"id":       {Filterable: true, Type: UuidField},
+		"status":   {Filterable: true, Type: EnumField},
+		"active":   {Filterable: true, Type: BoolField},
+		"priority": {Filterable: true, Values: map[string]interface{}{"low": 1, "medium": 2, "high": 3}},
+		"gender":   {Filterable: true, Type: EnumField, Values: map[string]interface{}{"f": nil, "m": "male"}},
+		"tags":     {Filterable: true, Type: StringField, Array: true},
+		"scores":   {Filterable: true, Type: IntField, Array: true},
+		"levels":   {Filterable: true, Array: true, Values: map[string]interface{}{"low": 1, "high": 2}},
+	}
+}
+
+// getMacroRegistry returns the user-defined macros used by test data.
+func getMacroRegistry() *MacroRegistry {
+	constant := func(v interface{}) MacroRenderer {
+		return func(args []interface{}) (interface{}, error) {
+			return v, nil
+		}
+	}
+	fiscalYearStart := func(args []interface{}) (interface{}, error) {
+		return time.Date(int(args[0].(int64))-1, 10, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	mr := NewMacroRegistry()
+	mr.Register("#tenant_id", &MacroDef{
+		Result:    IntField,
+		Renderers: map[string]MacroRenderer{"sql": constant(42), "mongo": constant(42), "elasticsearch": constant(42)},
+	})
+	mr.Register("#current_user", &MacroDef{
+		Result:    StringField,
+		Renderers: map[string]MacroRenderer{"sql": constant("jdoe"), "mongo": constant("jdoe")},
+	})
+	mr.Register("#fiscal_year_start", &MacroDef{
+		Params: []FieldType{IntField},
+		Result: DateField,
+		Renderers: map[string]MacroRenderer{
+			"sql": fiscalYearStart,
+			"postgres": func(args []interface{}) (interface{}, error) {
+				return NativeCode(fmt.Sprintf("MAKE_DATE(%d, 10, 1)", args[0].(int64)-1)), nil
+			},
+			"elasticsearch": fiscalYearStart,
+		},
+	})
+	mr.Register("#epoch", &MacroDef{
+		Result: DateTimeField,
+		Renderers: map[string]MacroRenderer{
+			"sql":           constant(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)),
+			"mongo":         constant(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)),
+			"elasticsearch": constant(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)),
+		},
+	})
+	mr.Register("#sql_only", &MacroDef{
+		Renderers: map[string]MacroRenderer{"sql": constant(1)},
+	})
+
+	return mr
+}
+
+// getClock returns a clock that always returns 2020-03-19T02:30:00Z, which is
+// still 2020-03-18 in the time zone returned by getLocation.
+func getClock() func() time.Time {
+	return func() time.Time {
+		return time.Date(2020, 3, 19, 2, 30, 0, 0, time.UTC)
+	}
+}
+
+// getLocation returns the time zone used by test data, i.e. UTC-5.
+func getLocation() *time.Location {
+	return time.FixedZone("EST", -5*60*60)
+}
+
+// getClockTestDataItems returns a map of target:testDataItems with predefined
+// test data for macros and literals resolved relative to the clock and time
+// zone returned by getClock and getLocation.
+func getClockTestDataItems() map[string][]testDataItem {
+	return map[string][]testDataItem{
+		"sql": {
+			{"ident lt #now", "ident < '2020-03-19 02:30:00'", false},
+			{"ident lt (#now sub #duration('PT1H'))", "ident < (TIMESTAMP '2020-03-19 02:30:00' - INTERVAL '1 HOUR')", false},
+			{"ident lt '2020-03-15T14:10:25'", "ident < '2020-03-15 19:10:25'", false},
+			{"ident lt '2020-03-15T14:10:25+02'", "ident < '2020-03-15 12:10:25'", false},
+			{"ident eq '2020-03-15'", "ident = '2020-03-15'", false},
+			{"ident eq #today", "ident = '2020-03-18'", false},
+			{"ident in (#today)", "ident IN (DATE '2020-03-18')", false},
+			{"ident gte (#today sub #duration('P1D'))", "ident >= (DATE '2020-03-18' - INTERVAL '1 DAY')", false},
+			{"ident gte #startof('day')", "ident >= '2020-03-18 05:00:00'", false},
+			{"ident gte #startof('week')", "ident >= '2020-03-16 05:00:00'", false},
+			{"ident gte #startof('month')", "ident >= '2020-03-01 05:00:00'", false},
+			{"ident gte #startof('quarter')", "ident >= '2020-01-01 05:00:00'", false},
+			{"ident gte #startof('year')", "ident >= '2020-01-01 05:00:00'", false},
+			{"ident lte #endof('week')", "ident <= '2020-03-23 04:59:59.999999'", false},
+			{"ident lte #endof('month')", "ident <= '2020-04-01 04:59:59.999999'", false},
+			{"ident gte #ago('P7D')", "ident >= '2020-03-12 02:30:00'", false},
+			{"ident gte #ago('P1W', 'PT12H')", "ident >= '2020-03-11 14:30:00'", false},
+			{"ident gte #ago('P1M')", "ident >= '2020-02-19 02:30:00'", false},
+			{"ident gte #ago('P1Y2M')", "ident >= '2019-01-19 02:30:00'", false},
+			{"ident gte #ago('PT1.5S')", "ident >= '2020-03-19 02:29:58.5'", false},
+			{"ident gte #ago('PT0.000001S')", "ident >= '2020-03-19 02:29:59.999999'", false},
+			{"ident gte #ago('-P1M')", "ident >= '2020-04-19 02:30:00'", false},
+			{"ident lt #now(1)", "", true},
+			{"ident gte #epoch", "ident >= '2020-01-01 12:00:00'", false},
+			{"ident gte #today(1)", "", true},
+			{"ident gte #startof('decade')", "", true},
+			{"ident gte #startof(1)", "", true},
+			{"ident gte #endof", "", true},
+			{"ident gte #ago", "", true},
+		},
+		"mongo": {
+			{"ident lt #now", `{"$expr":{"$lt":["$ident",{"$date":"2020-03-19T02:30:00Z"}]}}`, false},
+			{"ident lt '2020-03-15T14:10:25'", `{"ident":{"$lt":{"$date":"2020-03-15T19:10:25Z"}}}`, false},
+			{"ident eq '2020-03-15'", `{"ident":{"$eq":{"$date":"2020-03-15T05:00:00Z"}}}`, false},
+			{"ident in (#today)", `{"ident":{"$in":[{"$date":"2020-03-18T05:00:00Z"}]}}`, false},
+			{"ident in (#startof('month'))", `{"ident":{"$in":[{"$date":"2020-03-01T05:00:00Z"}]}}`, false},
+			{"ident in (#endof('week'))", `{"ident":{"$in":[{"$date":"2020-03-23T04:59:59.999Z"}]}}`, false},
+			{"ident gte #ago('P7D')", `{"$expr":{"$gte":["$ident",{"$date":"2020-03-12T02:30:00Z"}]}}`, false},
+			{"ident gte #startof('decade')", "", true},
+			{"ident gt #now(1)", "", true},
+			{"ident gte #epoch", `{"$expr":{"$gte":["$ident",{"$date":"2020-01-01T12:00:00Z"}]}}`, false},
+		},
+		"elasticsearch": {
+			{"ident lt #now", `{"range":{"ident":{"lt":"2020-03-19T02:30:00"}}}`, false},
+			{"ident gte (#now sub #duration('PT1H'))", `{"range":{"ident":{"gte":"2020-03-19T02:30:00||-1h"}}}`, false},
+			{"ident lt '2020-03-15T14:10:25'", `{"range":{"ident":{"lt":"2020-03-15T19:10:25"}}}`, false},
+			{"ident eq #today", `{"term":{"ident":"2020-03-18"}}`, false},
+			{"ident gte #startof('month')", `{"range":{"ident":{"gte":"2020-03-01T05:00:00"}}}`, false},
+			{"ident lte #endof('week')", `{"range":{"ident":{"lte":"2020-03-23T04:59:59.999999"}}}`, false},
+			{"ident gte #ago('P7D')", `{"range":{"ident":{"gte":"2020-03-12T02:30:00"}}}`, false},
+			{"ident gte (#today sub #duration('P1D'))", `{"range":{"ident":{"gte":"2020-03-18||-1d"}}}`, false},
+			{"ident gte #startof('decade')", "", true},
+			{"ident gt #now(1)", "", true},
+			{"ident lt (#now('x') sub #duration('PT1H'))", "", true},
+			{"ident gte #epoch", `{"range":{"ident":{"gte":"2020-01-01T12:00:00Z"}}}`, false},
+		},
+	}
+}
+
+// getMonthEndClock returns a clock that always returns 2020-03-31T12:00:00Z,
+// which is the last day of a month following a shorter one.
+func getMonthEndClock() func() time.Time {
+	return func() time.Time {
+		return time.Date(2020, 3, 31, 12, 0, 0, 0, time.UTC)
+	}
+}
+
+// getMonthEndTestDataItems returns an array of testDataItem structs with
+// predefined test data for calendar macros resolved relative to the clock
+// returned by getMonthEndClock.
+func getMonthEndTestDataItems() []testDataItem {
+	return []testDataItem{
+		{"ident gte #ago('P1M')", "ident >= '2020-02-29 12:00:00'", false},
+		{"ident gte #ago('P2M')", "ident >= '2020-01-31 12:00:00'", false},
+		{"ident gte #ago('P1M1D')", "ident >= '2020-02-28 12:00:00'", false},
+		{"ident gte #ago('P1Y1M')", "ident >= '2019-02-28 12:00:00'", false},
+		{"ident gte #ago('P1M', 'P1M')", "ident >= '2020-01-31 12:00:00'", false},
+		{"ident gte #startof('month')", "ident >= '2020-03-01 00:00:00'", false},
+		{"ident lte #endof('month')", "ident <= '2020-03-31 23:59:59.999999'", false},
+	}
+}
+
+// getClockNamedParamsTestDataItems returns an array of namedParamsTestDataItem
+// structs with predefined test data for macros and literals resolved relative
+// to the clock and time zone returned by getClock and getLocation.
+func getClockNamedParamsTestDataItems() []namedParamsTestDataItem {
+	loc := getLocation()
+
+	return []namedParamsTestDataItem{
+		{testDataItem{"ident lt #now", "ident < :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 18, 21, 30, 0, 0, loc)}}},
+		{testDataItem{"ident lt (#now sub #duration('PT2H'))", "ident < (DATEADD(HOUR, @P1, @P2))", false}, "sqlserver", []NamedParam{{"P1", int64(-2)}, {"P2", time.Date(2020, 3, 18, 21, 30, 0, 0, loc)}}},
+		{testDataItem{"ident eq '2020-03-15'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident eq '2020-03-15T14:10:25'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 14, 10, 25, 0, loc)}}},
+		{testDataItem{"ident eq '2020-03-15T14:10:25+02'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 14, 10, 25, 0, time.FixedZone("", 2*60*60))}}},
+		{testDataItem{"ident gte #epoch", "ident >= :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident eq #today", "ident = $1", false}, "postgres", []NamedParam{{"P1", time.Date(2020, 3, 18, 0, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident gte #startof('day') and ident lte #endof('day')", "ident >= :P1 AND ident <= :P2", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 18, 0, 0, 0, 0, loc)}, {"P2", time.Date(2020, 3, 18, 23, 59, 59, 999999000, loc)}}},
+	}
+}
+
+// getDialectTestDataItems returns a map of dialectName:testDataItems with
+// predefined test data for the built-in dialects.
+func getDialectTestDataItems() map[string][]testDataItem {
+	return map[string][]testDataItem{
+		"postgres": {
+			{"ident is true", "ident = TRUE", false},
+			{"is not ident", "ident = FALSE", false},
+			{"ident eq false", "ident = FALSE", false},
+			{"order eq 1", `"order" = 1`, false},
+			{"ident startswith 'text'", `ident LIKE 'text%' ESCAPE '\'`, false},
+			{"ident icontains 'te_xt'", `ident ILIKE '%te\_xt%' ESCAPE '\'`, false},
+			{"ident ieq 'text'", "LOWER(ident) = LOWER('text')", false},
+			{"ident matches '^te\\\\d+'", `ident ~ '^te\d+'`, false},
+			{"ident matches '\\\\btext'", "", true},
+			{"ident matches '(?i)text'", "", true},
+			{"json.address.city eq 'text'", "data->'address'->>'city' = 'text'", false},
+			{"ident lt (#now sub #duration('P1DT2H'))", "ident < (CURRENT_TIMESTAMP - INTERVAL '1 DAY 2 HOURS')", false},
+			{"ident lt ('2020-03-15T14:10:25' add #duration('PT2H'))", "ident < (TIMESTAMP '2020-03-15 14:10:25' + INTERVAL '2 HOURS')", false},
+			{"ident lt (#now add #duration('P1M', '-P1D'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '1 MONTH -1 DAYS')", false},
+			{"tags has 'vip'", "tags @> ARRAY['vip']", false},
+			{"roles any in ('admin', 'owner')", "roles && ARRAY['admin', 'owner']", false},
+			{"roles all in ('admin', 'owner')", "roles <@ ARRAY['admin', 'owner']", false},
+			{"scores any eq 50", "50 = ANY(scores)", false},
+			{"scores all gt 50", "50 < ALL(scores)", false},
+			{"dayofweek(ident) eq 1", "(EXTRACT(DOW FROM ident) + 1) = 1", false},
+			{"ident gte #fiscal_year_start(2020)", "ident >= MAKE_DATE(2019, 10, 1)", false},
+		},
+		"mysql": {
+			{"ident is true", "ident = TRUE", false},
+			{"order eq 1", "`order` = 1", false},
+			{"ident lt (#now sub #duration('P1DT2H'))", "ident < (CURRENT_TIMESTAMP - INTERVAL 1 DAY - INTERVAL 2 HOUR)", false},
+			{"ident lt (ident2 add #duration('P1W'))", "ident < (ident2 + INTERVAL 1 WEEK)", false},
+			{"ident lt (#now sub #duration('P1MT1.5S'))", "ident < (CURRENT_TIMESTAMP - INTERVAL 1 MONTH - INTERVAL 1 SECOND - INTERVAL 500000 MICROSECOND)", false},
+			{"ident lt (#now add #duration('-P1D'))", "ident < (CURRENT_TIMESTAMP - INTERVAL 1 DAY)", false},
+			{"ident startswith '50%'", `ident LIKE '50\\%%'`, false},
+			{"ident istartswith 'text'", "LOWER(ident) LIKE LOWER('text%')", false},
+			{"ident matches '^te\\\\d+'", `ident REGEXP '^te\\d+'`, false},
+			{"ident matches '(?P<name>text)'", "", true},
+			{"json.address.city eq 'text'", "JSON_EXTRACT(data, '$.address.city') = 'text'", false},
+			{"ident eq 'it\\'s'", "ident = 'it''s'", false},
+			{"tags has 'vip'", "'vip' MEMBER OF(tags)", false},
+			{"dayofweek(ident) eq 1", "DAYOFWEEK(ident) = 1", false},
+			{"scores all gt 50", "NOT EXISTS (SELECT 1 FROM JSON_TABLE(scores, '$[*]' COLUMNS (elem JSON PATH '$')) AS e WHERE NOT (elem > 50))", false},
+		},
+		"sqlite": {
+			{"ident is true", "ident = 1", false},
+			{"order eq 1", `"order" = 1`, false},
+			{"ident lt (#now sub #duration('P1DT2H'))", "ident < (datetime(CURRENT_TIMESTAMP, '-1 days', '-2 hours'))", false},
+			{"ident lt ('2020-03-15T14:10:25' add #duration('P1W'))", "ident < (datetime(datetime('2020-03-15 14:10:25'), '+7 days'))", false},
+			{"ident lt (#now sub #duration('P1MT1.5S'))", "ident < (datetime(CURRENT_TIMESTAMP, '-1 months', '-1.5 seconds'))", false},
+			{"ident matches 'te[xy]t'", "ident REGEXP 'te[xy]t'", false},
+			{"ident matches 'te.+?t'", "", true},
+			{"tags has 'vip'", "EXISTS (SELECT 1 FROM json_each(tags) WHERE value = 'vip')", false},
+			{"length(ident) gt 1", "LENGTH(ident) > 1", false},
+			{"year(ident) eq 2020", "CAST(strftime('%Y', ident) AS INTEGER) = 2020", false},
+			{"dayofweek(ident) eq 1", "(CAST(strftime('%w', ident) AS INTEGER) + 1) = 1", false},
+		},
+		"sqlserver": {
+			{"ident is not true", "ident != 1", false},
+			{"order eq 1", "[order] = 1", false},
+			{"ident contains '[a]'", `ident LIKE '%\[a]%' ESCAPE '\'`, false},
+			{"ident matches 'text'", "", true},
+			{"ident lt (#now sub #duration('P1DT2H'))", "ident < (DATEADD(HOUR, -2, DATEADD(DAY, -1, CURRENT_TIMESTAMP)))", false},
+			{"ident lt ('2020-03-15T14:10:25' add #duration('PT2H'))", "ident < (DATEADD(HOUR, 2, CAST('2020-03-15 14:10:25' AS DATETIME2)))", false},
+			{"ident lt ('2020-03-15T14:10:25-05:00' add #duration('PT2H'))", "ident < (DATEADD(HOUR, 2, CAST('2020-03-15 19:10:25' AS DATETIME2)))", false},
+			{"ident lt (#now add #duration('P1MT0.25S'))", "ident < (DATEADD(MICROSECOND, 250000, DATEADD(MONTH, 1, CURRENT_TIMESTAMP)))", false},
+			{"roles any in ('admin', 'owner')", "EXISTS (SELECT 1 FROM OPENJSON(roles) WHERE value IN ('admin', 'owner'))", false},
+			{"round(ident) eq 1", "ROUND(ident, 0) = 1", false},
+			{"ceil(ident) eq 1", "CEILING(ident) = 1", false},
+			{"year(ident) eq 2020", "DATEPART(YEAR, ident) = 2020", false},
+		},
+		"oracle": {
+			{"ident is true", "ident = 1", false},
+			{"order eq 1", `"order" = 1`, false},
+			{"ident lt (#now sub #duration('P1W'))", "ident < (CURRENT_TIMESTAMP - INTERVAL '7' DAY)", false},
+			{"ident lt (#now add #duration('P1DT2H'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '1' DAY + INTERVAL '2' HOUR)", false},
+			{"ident lt (#now add #duration('P1MT1.5S'))", "ident < (CURRENT_TIMESTAMP + INTERVAL '1' MONTH + INTERVAL '1.5' SECOND)", false},
+			{"ident matches 'te.+?t'", "REGEXP_LIKE(ident, 'te.+?t')", false},
+			{"dayofweek(ident) eq 1", "TO_NUMBER(TO_CHAR(ident, 'D')) = 1", false},
+			{"scores all lte 50", "NOT EXISTS (SELECT 1 FROM JSON_TABLE(scores, '$[*]' COLUMNS (elem VARCHAR2(4000) PATH '$')) WHERE NOT (elem <= 50))", false},
+		},
+	}
+}
+
+// getNamedParamsTestDataItems returns an array of namedParamsTestDataItem
+// structs with predefined test data.
+func getNamedParamsTestDataItems() []namedParamsTestDataItem {
+	return []namedParamsTestDataItem{
+		{testDataItem{"ident eq 'text'", "ident = :P1", false}, "generic", []NamedParam{{"P1", "text"}}},
+		{testDataItem{"ident startswith 'text'", `ident LIKE :P1 ESCAPE '\'`, false}, "generic", []NamedParam{{"P1", "text%"}}},
+		{testDataItem{"ident endswith '50%'", `ident LIKE :P1 ESCAPE '\'`, false}, "generic", []NamedParam{{"P1", "%50\\%"}}},
+		{testDataItem{"ident1 contains 'text' and ident2 eq 1", `ident1 LIKE :P1 ESCAPE '\' AND ident2 = :P2`, false}, "generic", []NamedParam{{"P1", "%text%"}, {"P2", int64(1)}}},
+		{testDataItem{"ident1 eq .5 and ident2 eq true", "ident1 = :P1 AND ident2 = :P2", false}, "generic", []NamedParam{{"P1", .5}, {"P2", true}}},
+		{testDataItem{"ident eq '2020-03-15'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)}}},
+		{testDataItem{"ident eq '2020-03-15T14:10:25+02'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 14, 10, 25, 0, time.FixedZone("", 2*60*60))}}},
+		{testDataItem{"ident eq '2020-03-15T14:10:25-05:30'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 14, 10, 25, 0, time.FixedZone("", -(5*60+30)*60))}}},
+		{testDataItem{"ident eq '2020-03-15T14:10:25Z'", "ident = :P1", false}, "generic", []NamedParam{{"P1", time.Date(2020, 3, 15, 14, 10, 25, 0, time.UTC)}}},
+		{testDataItem{"ident eq '15:30:55'", "ident = :P1", false}, "generic", []NamedParam{{"P1", "15:30:55"}}},
+		{testDataItem{"ident matches '^te.t'", "ident ~ $1", false}, "postgres", []NamedParam{{"P1", "^te.t"}}},
+		{testDataItem{"ident icontains 'text'", `ident ILIKE $1 ESCAPE '\'`, false}, "postgres", []NamedParam{{"P1", "%text%"}}},
+		{testDataItem{"ident ieq 'Text'", "LOWER(ident) = LOWER(:P1)", false}, "generic", []NamedParam{{"P1", "Text"}}},
+		{testDataItem{"ident1 in ('text1', 'text2') and ident2 not in (1)", "ident1 IN (:P1, :P2) AND ident2 NOT IN (:P3)", false}, "generic", []NamedParam{{"P1", "text1"}, {"P2", "text2"}, {"P3", int64(1)}}},
+		{testDataItem{"tags has 'vip' and scores any gt 50", "tags @> ARRAY[$1] AND $2 < ANY(scores)", false}, "postgres", []NamedParam{{"P1", "vip"}, {"P2", int64(50)}}},
+		{testDataItem{"ident eq #current_user", "ident = :P1", false}, "generic", []NamedParam{{"P1", "jdoe"}}},
+		{testDataItem{"round(ident, 2) gt 1", "ROUND(ident, :P1) > :P2", false}, "generic", []NamedParam{{"P1", int64(2)}, {"P2", int64(1)}}},
+		{testDataItem{"ident lt (#now sub #duration('P1DT2H'))", "ident < (CURRENT_TIMESTAMP - CAST(:P1 AS INTERVAL))", false}, "generic", []NamedParam{{"P1", "1 DAY 2 HOURS"}}},
+		{testDataItem{"ident lt (#now sub #duration('P1DT2H'))", "ident < (CURRENT_TIMESTAMP - INTERVAL ? DAY - INTERVAL ? HOUR)", false}, "mysql", []NamedParam{{"P1", int64(1)}, {"P2", int64(2)}}},
+		{testDataItem{"ident lt (#now add #duration('P1W'))", "ident < (datetime(CURRENT_TIMESTAMP, ?))", false}, "sqlite", []NamedParam{{"P1", "+7 days"}}},
+		{testDataItem{"ident lt (#now sub #duration('PT2H'))", "ident < (DATEADD(HOUR, @P1, CURRENT_TIMESTAMP))", false}, "sqlserver", []NamedParam{{"P1", int64(-2)}}},
+		{testDataItem{"ident lt (#now add #duration('P1Y'))", "ident < (CURRENT_TIMESTAMP + NUMTOYMINTERVAL(:P1, 'YEAR'))", false}, "oracle", []NamedParam{{"P1", int64(1)}}},
+		{testDataItem{"ident lt (#now add #duration('P1MT1.5S'))", "ident < (CURRENT_TIMESTAMP + NUMTOYMINTERVAL(:P1, 'MONTH') + NUMTODSINTERVAL(:P2, 'SECOND'))", false}, "oracle", []NamedParam{{"P1", int64(1)}, {"P2", 1.5}}},
+		{testDataItem{"ident lt (#now sub #duration('P1M'))", "ident < (CURRENT_TIMESTAMP - INTERVAL ? MONTH)", false}, "mysql", []NamedParam{{"P1", int64(1)}}},
 	}
 }
@@ -0,0 +1,220 @@
+/**
+ * @begin 2026-10-16
+ * @author <a href="mailto:giuseppe.greco@skeeterhealth.com">Giuseppe Greco</a>
+ * @copyright 2020 <a href="skeeterhealth.com">Skeeter</a>
+ */
+
+package espressopp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type termType int
+
+const (
+	undefType termType = iota
+	identType
+	intType
+	decimalType
+	stringType
+	dateType
+	timeType
+	dateTimeType
+	boolType
+	uuidType
+	enumType
+	arrayType
+)
+
+// coercions maps any type to the types whose values can be safely coerced to it.
+var coercions = map[termType][]termType{
+	decimalType:  {intType},
+	dateTimeType: {dateType},
+	uuidType:     {stringType},
+	enumType:     {stringType},
+}
+
+// validateTypes verifies whether or not t1 and t2 are compatible, and if they are,
+// it returns the result type of the current expression.
+func validateTypes(t1 termType, t2 termType) (termType, error) {
+	var err error
+	var t termType
+
+	if t1 == t2 {
+		t = t1
+	} else if t1 == identType {
+		t = t2
+	} else if t2 == identType {
+		t = t1
+	} else if coercible(t2, t1) {
+		t = t1
+	} else if coercible(t1, t2) {
+		t = t2
+	} else {
+		err = errors.Errorf("type %s is not compatible with type %s", toTypeName(t1), toTypeName(t2))
+	}
+
+	return t, err
+}
+
+// coercible returns a Boolean value indicating whether or not values of type
+// from can be safely coerced to type to.
+func coercible(from termType, to termType) bool {
+	for _, t := range coercions[to] {
+		if t == from {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOrdered returns a Boolean value indicating whether or not values of type t
+// can be compared with gt, gte, lt, and lte. Fields of undeclared type are
+// assumed to be ordered since their type is unknown until rendering.
+func isOrdered(t termType) bool {
+	return t == identType || t == intType || t == decimalType || t == dateType || t == timeType || t == dateTimeType
+}
+
+// toTypeName returns the name of t.
+func toTypeName(t termType) string {
+	var n string
+
+	switch t {
+	case identType:
+		n = "any"
+	case stringType:
+		n = "string"
+	case intType:
+		n = "int"
+	case decimalType:
+		n = "decimal"
+	case dateType:
+		n = "date"
+	case timeType:
+		n = "time"
+	case dateTimeType:
+		n = "datetime"
+	case boolType:
+		n = "bool"
+	case uuidType:
+		n = "uuid"
+	case enumType:
+		n = "enum"
+	case arrayType:
+		n = "array"
+	}
+
+	return n
+}
+
+// termTypeOf returns the type of t without rendering it. Function calls are of
+// any type since their type depends on their arguments.
+func termTypeOf(t *Term) termType {
+	var tt termType
+
+	if t.Identifier != nil || t.Function != nil {
+		tt = identType
+	} else if t.Integer != nil {
+		tt = intType
+	} else if t.Decimal != nil {
+		tt = decimalType
+	} else if t.String != nil {
+		tt = stringType
+	} else if t.Date != nil {
+		tt = dateType
+	} else if t.Time != nil {
+		tt = timeType
+	} else if t.DateTime != nil {
+		tt = dateTimeType
+	} else if t.Bool != nil {
+		tt = boolType
+	}
+
+	return tt
+}
+
+// toValue converts the literal s of type t into a Go value of the corresponding
+// type: int64, float64, bool, time.Time, or string. Times of day are returned
+// as strings since they do not denote an instant. Dates, times, and datetimes
+// are validated as real calendar dates and times of day.
+func toValue(s string, t termType) (interface{}, error) {
+	return toValueIn(s, t, time.UTC)
+}
+
+// toValueIn is like toValue, except that datetimes without a time zone offset
+// are interpreted in loc. Dates denote calendar days rather than instants, so
+// they are always returned as midnight UTC, which drivers send as is.
+func toValueIn(s string, t termType, loc *time.Location) (interface{}, error) {
+	var v interface{}
+	var err error
+
+	switch t {
+	case intType:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case decimalType:
+		v, err = strconv.ParseFloat(s, 64)
+	case boolType:
+		v, err = strconv.ParseBool(s)
+	case dateType:
+		v, err = time.Parse("2006-01-02", s)
+	case timeType:
+		v = s
+		_, err = time.Parse("15:04:05", s)
+	case dateTimeType:
+		s := strings.ToUpper(strings.Replace(s, " ", "T", 1))
+		var d time.Time
+		if d, err = time.ParseInLocation(dateTimeLayout(s), s, loc); err == nil {
+			if _, offset := d.Zone(); offset <= -24*60*60 || offset >= 24*60*60 {
+				err = errors.Errorf("time zone offset out of range")
+			}
+		}
+		v = d
+	default:
+		v = s
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s literal %s", toTypeName(t), s)
+	}
+
+	return v, nil
+}
+
+// hasOffset returns a Boolean value indicating whether or not the datetime
+// literal s specifies a time zone offset, i.e. Z, +hh, or +hh:mm.
+func hasOffset(s string) bool {
+	return strings.LastIndexAny(s, "+-Zz") > len("2006-01-02")
+}
+
+// dateTimeLayout returns the layout of the RFC 3339 datetime literal s, where
+// the time zone offset can be omitted or lack minutes.
+func dateTimeLayout(s string) string {
+	layout := "2006-01-02T15:04:05"
+
+	if i := strings.LastIndexAny(s, "+-Zz"); i <= len("2006-01-02") {
+		return layout
+	} else if s[i] == 'Z' || s[i] == 'z' {
+		return layout + "Z07:00"
+	} else if strings.Contains(s[i:], ":") {
+		return layout + "-07:00"
+	}
+
+	return layout + "-07"
+}
+
+// toUTCDateTime returns the datetime literal s in UTC, where datetimes without
+// a time zone offset are interpreted in loc.
+func toUTCDateTime(s string, loc *time.Location) (string, error) {
+	v, err := toValueIn(s, dateTimeType, loc)
+	if err != nil {
+		return "", err
+	}
+
+	return v.(time.Time).UTC().Format("2006-01-02T15:04:05.999999999"), nil
+}